@@ -14,14 +14,24 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/documind/vector-service/internal/api"
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/internal/wal"
 )
 
 func main() {
 	// Command-line flags
 	port := flag.Int("port", 8001, "Port to listen on")
 	dimensions := flag.Int("dimensions", 384, "Vector dimensions (default: 384 for all-MiniLM-L6-v2)")
+	metricName := flag.String("metric", "cosine", "Distance metric: cosine, l2, inner_product, or hamming")
+	walDir := flag.String("wal-dir", "", "Directory for the write-ahead log and snapshots; empty disables persistence")
+	walFsync := flag.String("wal-fsync", "always", "WAL fsync policy: always, interval, or never")
+	walFsyncInterval := flag.Duration("wal-fsync-interval", time.Second, "Fsync period when --wal-fsync=interval")
+	walSnapshotThreshold := flag.Int64("wal-snapshot-threshold-bytes", 64<<20, "Snapshot and truncate the WAL once it exceeds this size; <= 0 disables automatic snapshotting")
+	walSnapshotCheckInterval := flag.Duration("wal-snapshot-check-interval", 30*time.Second, "How often a background goroutine checks whether the WAL has grown past --wal-snapshot-threshold-bytes; <= 0 disables the check")
+	numShards := flag.Int("num-shards", 1, "Split the index into this many shards, routed by consistent hashing on tenant_id/document_id; 1 disables sharding. Not yet supported together with --wal-dir")
 	flag.Parse()
 
 	// Check for environment variable override
@@ -31,9 +41,48 @@ func main() {
 	if envDim := os.Getenv("VECTOR_DIMENSIONS"); envDim != "" {
 		fmt.Sscanf(envDim, "%d", dimensions)
 	}
+	if envMetric := os.Getenv("VECTOR_SERVICE_METRIC"); envMetric != "" {
+		*metricName = envMetric
+	}
+	if envWALDir := os.Getenv("VECTOR_SERVICE_WAL_DIR"); envWALDir != "" {
+		*walDir = envWALDir
+	}
 
-	// Initialize handler with indexes
-	handler := api.NewHandler(*dimensions)
+	metric, ok := index.DistanceByName(*metricName)
+	if !ok {
+		log.Fatalf("Unknown metric %q", *metricName)
+	}
+
+	// Initialize handler with indexes, optionally recovering from a WAL
+	// and rebuilding them from a prior run's snapshot first.
+	opts := []api.Option{api.WithMetric(metric)}
+	if *numShards > 1 {
+		opts = append(opts, api.WithNumShards(*numShards))
+	}
+	if *walDir != "" {
+		policy := wal.FsyncPolicy(*walFsync)
+		switch policy {
+		case wal.FsyncAlways, wal.FsyncInterval, wal.FsyncNever:
+		default:
+			log.Fatalf("Unknown --wal-fsync policy %q", *walFsync)
+		}
+		opts = append(opts,
+			api.WithWALDir(*walDir),
+			api.WithFsyncPolicy(policy),
+			api.WithFsyncInterval(*walFsyncInterval),
+			api.WithSnapshotThresholdBytes(*walSnapshotThreshold),
+			api.WithSnapshotInterval(*walSnapshotCheckInterval),
+		)
+	}
+
+	replayStart := time.Now()
+	handler, err := api.NewHandler(*dimensions, opts...)
+	if err != nil {
+		log.Fatalf("Failed to initialize handler: %v", err)
+	}
+	if *walDir != "" {
+		log.Printf("💾 Recovered from WAL in %v (dir: %s)", time.Since(replayStart), *walDir)
+	}
 
 	// Create router
 	router := api.NewRouter(handler)
@@ -45,7 +94,11 @@ func main() {
 	log.Printf("📡 Endpoints:")
 	log.Printf("   POST /insert       - Insert single vector")
 	log.Printf("   POST /insert/batch - Insert multiple vectors")
+	log.Printf("   POST /delete       - Delete single vector")
+	log.Printf("   POST /delete/batch - Delete multiple vectors")
+	log.Printf("   POST /upsert       - Insert or replace a vector by ID")
 	log.Printf("   POST /search       - Search for similar vectors")
+	log.Printf("   POST /snapshot     - Force a WAL snapshot")
 	log.Printf("   GET  /health       - Health check")
 	log.Printf("   GET  /stats        - Index statistics")
 
@@ -0,0 +1,124 @@
+// Tests for HandleDelete, HandleDeleteBatch, and HandleUpsert.
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/documind/vector-service/internal/api"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestHandleDeleteRemovesVector checks that a deleted vector no longer shows
+// up in the index's count and that deleting it again reports not found.
+func TestHandleDeleteRemovesVector(t *testing.T) {
+	h, err := api.NewHandler(3)
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+	doInsert(t, h, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+
+	req := httptest.NewRequest(http.MethodPost, "/delete", bytes.NewReader(mustMarshal(t, types.DeleteRequest{ID: "a"})))
+	rec := httptest.NewRecorder()
+	h.HandleDelete(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := vectorCount(t, h); got != 0 {
+		t.Fatalf("expected 0 vectors after delete, got %d", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/delete", bytes.NewReader(mustMarshal(t, types.DeleteRequest{ID: "a"})))
+	rec = httptest.NewRecorder()
+	h.HandleDelete(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting an already-removed vector, got %d", rec.Code)
+	}
+}
+
+// TestHandleDeleteBatchRemovesAll checks that a batch delete removes every
+// listed ID.
+func TestHandleDeleteBatchRemovesAll(t *testing.T) {
+	h, err := api.NewHandler(3)
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+	doInsert(t, h, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	doInsert(t, h, types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+
+	req := httptest.NewRequest(http.MethodPost, "/delete/batch", bytes.NewReader(mustMarshal(t, types.DeleteBatchRequest{IDs: []string{"a", "b"}})))
+	rec := httptest.NewRecorder()
+	h.HandleDeleteBatch(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp types.DeleteBatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.Deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", resp.Deleted)
+	}
+	if got := vectorCount(t, h); got != 0 {
+		t.Fatalf("expected 0 vectors after batch delete, got %d", got)
+	}
+}
+
+// TestHandleUpsertInsertsThenReplaces checks that upserting a new ID
+// reports Created=true and that upserting the same ID again replaces its
+// embedding in place, reporting Created=false, without growing the count.
+func TestHandleUpsertInsertsThenReplaces(t *testing.T) {
+	h, err := api.NewHandler(3)
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+
+	upsert := func(embedding []float32) types.UpsertResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/upsert", bytes.NewReader(mustMarshal(t, types.UpsertRequest{
+			ID:        "a",
+			Embedding: embedding,
+		})))
+		rec := httptest.NewRecorder()
+		h.HandleUpsert(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("upsert: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp types.UpsertResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := upsert([]float32{1, 0, 0}); !resp.Created {
+		t.Errorf("expected first upsert to report Created=true, got %+v", resp)
+	}
+	if got := vectorCount(t, h); got != 1 {
+		t.Fatalf("expected 1 vector after first upsert, got %d", got)
+	}
+
+	if resp := upsert([]float32{0, 1, 0}); resp.Created {
+		t.Errorf("expected second upsert to report Created=false, got %+v", resp)
+	}
+	if got := vectorCount(t, h); got != 1 {
+		t.Fatalf("expected upsert to replace rather than add, got %d vectors", got)
+	}
+
+	searchReq := httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(mustMarshal(t, types.SearchRequest{
+		Embedding: []float32{0, 1, 0},
+		TopK:      1,
+	})))
+	searchRec := httptest.NewRecorder()
+	h.HandleSearch(searchRec, searchReq)
+	var searchResp types.SearchResponse
+	if err := json.Unmarshal(searchRec.Body.Bytes(), &searchResp); err != nil {
+		t.Fatalf("unmarshal search response: %v", err)
+	}
+	if len(searchResp.Results) != 1 || searchResp.Results[0].Score < 0.99 {
+		t.Fatalf("expected upsert's replacement embedding to be the one searched, got %+v", searchResp.Results)
+	}
+}
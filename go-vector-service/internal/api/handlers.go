@@ -3,10 +3,15 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/internal/wal"
+	"github.com/documind/vector-service/pkg/quantize"
 	"github.com/documind/vector-service/pkg/types"
 )
 
@@ -15,17 +20,255 @@ type Handler struct {
 	bruteForce *index.BruteForceIndex
 	hnsw       *index.HNSWIndex
 	dimensions int
+	metric     index.Distance
+
+	// sharded is non-nil when WithNumShards was passed a value > 1, in
+	// which case HandleInsert/HandleInsertBatch/HandleSearch route through
+	// it instead of bruteForce/hnsw above, which are left unused. Not yet
+	// supported together with WithWALDir - see NewHandler.
+	sharded *index.ShardedIndex
+
+	// hnswPQ and pqCodec back the "hnsw-pq" index mode. Both are nil until
+	// POST /index/pq/train has been called; pqMu guards their
+	// train-then-read lifecycle since training replaces them wholesale
+	// rather than mutating in place.
+	pqMu    sync.RWMutex
+	pqCodec *quantize.PQ
+	hnswPQ  *index.HNSWPQIndex
+
+	// wal is nil unless WithWALDir was passed to NewHandler, in which case
+	// every Insert/InsertBatch is logged before being applied so a crash
+	// can be recovered from by replaying the log at startup. snapMu guards
+	// the snapshot-then-truncate sequence in snapshot so two snapshots (one
+	// triggered by size, one by POST /snapshot, one by the background
+	// rotation goroutine) can't race.
+	wal                    *wal.WAL
+	walDir                 string
+	snapshotThresholdBytes int64
+	snapMu                 sync.Mutex
+	lastSnapshotSeq        uint64
+	replayDurationMs       float64
+
+	// stopSnapshotLoop and snapshotLoopWg control the background goroutine
+	// WithSnapshotInterval starts; both are nil/unused if that option
+	// wasn't passed.
+	stopSnapshotLoop chan struct{}
+	snapshotLoopWg   sync.WaitGroup
+}
+
+// handlerConfig collects NewHandler's optional settings; see the With*
+// functions below. Unexported since Option is the only supported way to
+// populate it.
+type handlerConfig struct {
+	metric                 index.Distance
+	walDir                 string
+	fsyncPolicy            wal.FsyncPolicy
+	fsyncInterval          time.Duration
+	snapshotThresholdBytes int64
+	snapshotInterval       time.Duration
+	numShards              int
+}
+
+// Option configures a Handler built by NewHandler.
+type Option func(*handlerConfig)
+
+// WithMetric scores the handler's indexes with metric instead of the
+// default cosine similarity.
+func WithMetric(metric index.Distance) Option {
+	return func(c *handlerConfig) { c.metric = metric }
+}
+
+// WithWALDir enables WAL-backed persistence in dir: on construction, the
+// latest snapshot already there (if any) is loaded and the WAL segment
+// left behind by a prior run is replayed on top of it, then the WAL is
+// reopened for appending. Without this option a Handler is purely
+// in-memory.
+func WithWALDir(dir string) Option {
+	return func(c *handlerConfig) { c.walDir = dir }
 }
 
-// NewHandler creates a new Handler with initialized indexes.
-func NewHandler(dimensions int) *Handler {
-	return &Handler{
-		bruteForce: index.NewBruteForceIndex(dimensions),
-		hnsw:       index.NewHNSWIndex(index.DefaultHNSWConfig(dimensions)),
-		dimensions: dimensions,
+// WithFsyncPolicy sets the WAL's fsync policy; only meaningful alongside
+// WithWALDir. Defaults to wal.FsyncAlways.
+func WithFsyncPolicy(policy wal.FsyncPolicy) Option {
+	return func(c *handlerConfig) { c.fsyncPolicy = policy }
+}
+
+// WithFsyncInterval sets the fsync period used when the policy passed to
+// WithFsyncPolicy is wal.FsyncInterval.
+func WithFsyncInterval(d time.Duration) Option {
+	return func(c *handlerConfig) { c.fsyncInterval = d }
+}
+
+// WithSnapshotThresholdBytes triggers an automatic snapshot-and-truncate
+// once the WAL grows past this many bytes. <= 0 disables size-triggered
+// snapshotting. Defaults to 64MiB.
+func WithSnapshotThresholdBytes(n int64) Option {
+	return func(c *handlerConfig) { c.snapshotThresholdBytes = n }
+}
+
+// WithSnapshotInterval starts a background goroutine that checks every d
+// whether a snapshot is due (per WithSnapshotThresholdBytes), rotating the
+// WAL on a timer in addition to the check already made right after each
+// write. Disabled (0) by default; stopped by Handler.Close.
+func WithSnapshotInterval(d time.Duration) Option {
+	return func(c *handlerConfig) { c.snapshotInterval = d }
+}
+
+// WithNumShards splits the index into n shards, routed by consistent
+// hashing on Vector.Metadata.TenantID (or DocumentID if that's unset); see
+// index.ShardedIndex. n <= 1 (the default) keeps the single-shard behavior
+// every other option assumes. Not yet supported together with WithWALDir.
+func WithNumShards(n int) Option {
+	return func(c *handlerConfig) { c.numShards = n }
+}
+
+func defaultHandlerConfig() handlerConfig {
+	return handlerConfig{
+		metric:                 index.Cosine{},
+		fsyncPolicy:            wal.FsyncAlways,
+		snapshotThresholdBytes: 64 << 20,
+		numShards:              1,
 	}
 }
 
+// NewHandler creates a Handler for dimensions-dimensional vectors. With no
+// options it scores results with cosine similarity and keeps everything
+// in memory; WithWALDir adds crash-recoverable persistence.
+func NewHandler(dimensions int, opts ...Option) (*Handler, error) {
+	cfg := defaultHandlerConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	hnswConfig := index.DefaultHNSWConfig(dimensions)
+	hnswConfig.Metric = cfg.metric
+	h := &Handler{
+		bruteForce:             index.NewBruteForceIndexWithMetric(dimensions, cfg.metric),
+		hnsw:                   index.NewHNSWIndex(hnswConfig),
+		dimensions:             dimensions,
+		metric:                 cfg.metric,
+		snapshotThresholdBytes: cfg.snapshotThresholdBytes,
+	}
+
+	if cfg.numShards > 1 {
+		if cfg.walDir != "" {
+			return nil, fmt.Errorf("api: WithNumShards > 1 is not yet supported together with WithWALDir")
+		}
+		h.sharded = index.NewShardedIndex(cfg.numShards, hnswConfig)
+	}
+
+	if cfg.walDir == "" {
+		return h, nil
+	}
+	h.walDir = cfg.walDir
+
+	if path, seq, ok, err := wal.FindLatestSnapshot(cfg.walDir); err != nil {
+		return nil, fmt.Errorf("api: find latest snapshot: %w", err)
+	} else if ok {
+		if err := wal.ReadSnapshot(path, h.bruteForce, h.hnsw); err != nil {
+			return nil, fmt.Errorf("api: load snapshot: %w", err)
+		}
+		h.lastSnapshotSeq = seq
+	}
+
+	replayStart := time.Now()
+	onInsert := func(v types.Vector) error {
+		if err := h.bruteForce.Insert(v); err != nil {
+			return err
+		}
+		return h.hnsw.Insert(v)
+	}
+	onDelete := func(id string) error {
+		if err := h.bruteForce.Delete(id); err != nil {
+			return err
+		}
+		return h.hnsw.Delete(id)
+	}
+	if _, _, err := wal.Replay(cfg.walDir, onInsert, onDelete); err != nil {
+		return nil, fmt.Errorf("api: replay wal: %w", err)
+	}
+	h.replayDurationMs = float64(time.Since(replayStart).Microseconds()) / 1000.0
+
+	w, err := wal.Open(cfg.walDir, cfg.fsyncPolicy, cfg.fsyncInterval)
+	if err != nil {
+		return nil, fmt.Errorf("api: open wal: %w", err)
+	}
+	h.wal = w
+
+	if cfg.snapshotInterval > 0 {
+		h.stopSnapshotLoop = make(chan struct{})
+		h.snapshotLoopWg.Add(1)
+		go h.snapshotLoop(cfg.snapshotInterval)
+	}
+
+	return h, nil
+}
+
+// snapshotLoop periodically calls maybeSnapshot so the WAL gets rotated on
+// a timer even during a lull between writes, not just right after one.
+func (h *Handler) snapshotLoop(interval time.Duration) {
+	defer h.snapshotLoopWg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.maybeSnapshot()
+		case <-h.stopSnapshotLoop:
+			return
+		}
+	}
+}
+
+// Close stops the background snapshot-rotation goroutine (if
+// WithSnapshotInterval was used) and closes the WAL (if WithWALDir was
+// used). Safe to call on a Handler built without either option.
+func (h *Handler) Close() error {
+	if h.stopSnapshotLoop != nil {
+		close(h.stopSnapshotLoop)
+		h.snapshotLoopWg.Wait()
+	}
+	if h.wal != nil {
+		return h.wal.Close()
+	}
+	return nil
+}
+
+// snapshot serializes both indexes to a new snapshot-<seq>.bin file and
+// truncates the WAL, returning the sequence number the snapshot was taken
+// at. Safe to call concurrently with inserts; it only needs a consistent
+// read of each index, which Save already provides.
+func (h *Handler) snapshot() (uint64, error) {
+	h.snapMu.Lock()
+	defer h.snapMu.Unlock()
+
+	seq := h.wal.Seq()
+	if err := wal.WriteSnapshot(h.walDir, seq, h.bruteForce, h.hnsw); err != nil {
+		return 0, err
+	}
+	if err := h.wal.Reset(); err != nil {
+		return 0, err
+	}
+	h.lastSnapshotSeq = seq
+	return seq, nil
+}
+
+// maybeSnapshot triggers a snapshot if the WAL has grown past
+// snapshotThresholdBytes. Errors are logged by the caller's sendJSON error
+// path only for the explicit POST /snapshot handler; here a failed
+// best-effort snapshot just leaves the WAL to keep growing, to be retried
+// after the next write or on the next background check.
+func (h *Handler) maybeSnapshot() {
+	if h.wal == nil || h.snapshotThresholdBytes <= 0 {
+		return
+	}
+	size, err := h.wal.Size()
+	if err != nil || size < h.snapshotThresholdBytes {
+		return
+	}
+	h.snapshot()
+}
+
 // HandleInsert handles POST /insert requests.
 func (h *Handler) HandleInsert(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -51,6 +294,14 @@ func (h *Handler) HandleInsert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Metric != "" && req.Metric != h.metric.Name() {
+		sendJSON(w, http.StatusBadRequest, types.InsertResponse{
+			Success: false,
+			Message: "Metric mismatch: index uses \"" + h.metric.Name() + "\", got \"" + req.Metric + "\"",
+		})
+		return
+	}
+
 	// Create vector
 	v := types.Vector{
 		ID:        req.ID,
@@ -58,6 +309,65 @@ func (h *Handler) HandleInsert(w http.ResponseWriter, r *http.Request) {
 		Metadata:  req.Metadata,
 	}
 
+	// ?index=hnsw-pq routes to the PQ-compressed index instead of the
+	// default brute-force+HNSW pair, since it needs a trained codec no
+	// other index depends on.
+	if r.URL.Query().Get("index") == "hnsw-pq" {
+		h.pqMu.RLock()
+		hnswPQ := h.hnswPQ
+		h.pqMu.RUnlock()
+		if hnswPQ == nil {
+			sendJSON(w, http.StatusBadRequest, types.InsertResponse{
+				Success: false,
+				Message: "hnsw-pq index has not been trained yet; call POST /index/pq/train first",
+			})
+			return
+		}
+		if err := hnswPQ.Insert(v); err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.InsertResponse{
+				Success: false,
+				Message: "Failed to insert into hnsw-pq index: " + err.Error(),
+			})
+			return
+		}
+		sendJSON(w, http.StatusOK, types.InsertResponse{
+			Success: true,
+			Message: "Vector inserted successfully",
+		})
+		return
+	}
+
+	// Sharded mode routes to a ShardedIndex instead of the bruteForce/hnsw
+	// pair below, and doesn't support the WAL (see WithNumShards), so there
+	// is no crash-recovery log to append to here.
+	if h.sharded != nil {
+		if err := h.sharded.Insert(v); err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.InsertResponse{
+				Success: false,
+				Message: "Failed to insert into sharded index: " + err.Error(),
+			})
+			return
+		}
+		sendJSON(w, http.StatusOK, types.InsertResponse{
+			Success: true,
+			Message: "Vector inserted successfully",
+		})
+		return
+	}
+
+	// Log the write before applying it, so a crash between the two leaves a
+	// WAL record to replay rather than an acknowledged insert that never
+	// happened.
+	if h.wal != nil {
+		if _, err := h.wal.AppendInsert(v); err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.InsertResponse{
+				Success: false,
+				Message: "Failed to append to WAL: " + err.Error(),
+			})
+			return
+		}
+	}
+
 	// Insert into both indexes
 	if err := h.bruteForce.Insert(v); err != nil {
 		sendJSON(w, http.StatusInternalServerError, types.InsertResponse{
@@ -75,6 +385,8 @@ func (h *Handler) HandleInsert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.maybeSnapshot()
+
 	sendJSON(w, http.StatusOK, types.InsertResponse{
 		Success: true,
 		Message: "Vector inserted successfully",
@@ -108,6 +420,39 @@ func (h *Handler) HandleInsertBatch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// See HandleInsert: sharded mode bypasses the WAL and the bruteForce/hnsw
+	// pair below entirely.
+	if h.sharded != nil {
+		inserted, err := h.sharded.InsertBatch(req.Vectors)
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.InsertBatchResponse{
+				Inserted: inserted,
+				Message:  "Failed to insert into sharded index: " + err.Error(),
+			})
+			return
+		}
+		sendJSON(w, http.StatusOK, types.InsertBatchResponse{
+			Inserted: inserted,
+			Message:  "Vectors inserted successfully",
+		})
+		return
+	}
+
+	// Log every vector in the batch before applying any of it, one record
+	// per vector, so a crash partway through leaves exactly the WAL entries
+	// needed to replay the rest.
+	if h.wal != nil {
+		for _, v := range req.Vectors {
+			if _, err := h.wal.AppendInsert(v); err != nil {
+				sendJSON(w, http.StatusInternalServerError, types.InsertBatchResponse{
+					Inserted: 0,
+					Message:  "Failed to append to WAL: " + err.Error(),
+				})
+				return
+			}
+		}
+	}
+
 	// Insert into both indexes
 	countBF, err := h.bruteForce.InsertBatch(req.Vectors)
 	if err != nil {
@@ -118,7 +463,15 @@ func (h *Handler) HandleInsertBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.hnsw.InsertBatch(req.Vectors)
+	// ?parallel=N shards the HNSW insert across N workers instead of the
+	// default one-at-a-time InsertBatch; the brute-force index has no
+	// per-node locking to exploit, so it always inserts serially above.
+	parallel, parseErr := strconv.Atoi(r.URL.Query().Get("parallel"))
+	if parseErr == nil && parallel > 0 {
+		_, err = h.hnsw.InsertParallel(req.Vectors, parallel)
+	} else {
+		_, err = h.hnsw.InsertBatch(req.Vectors)
+	}
 	if err != nil {
 		sendJSON(w, http.StatusInternalServerError, types.InsertBatchResponse{
 			Inserted: countBF,
@@ -127,12 +480,289 @@ func (h *Handler) HandleInsertBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.maybeSnapshot()
+
 	sendJSON(w, http.StatusOK, types.InsertBatchResponse{
 		Inserted: countBF,
 		Message:  "Vectors inserted successfully",
 	})
 }
 
+// HandleDelete handles POST /delete requests.
+func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.DeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSON(w, http.StatusBadRequest, types.DeleteResponse{
+			Success: false,
+			Message: "Invalid JSON: " + err.Error(),
+		})
+		return
+	}
+
+	// See HandleInsert: sharded mode bypasses the WAL and the bruteForce/hnsw
+	// pair below entirely.
+	if h.sharded != nil {
+		if err := h.sharded.Delete(req.ID); err != nil {
+			sendJSON(w, http.StatusNotFound, types.DeleteResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+		sendJSON(w, http.StatusOK, types.DeleteResponse{
+			Success: true,
+			Message: "Vector deleted successfully",
+		})
+		return
+	}
+
+	// Check the ID actually exists before touching the WAL: a delete for an
+	// ID that's already gone (a double-delete, a retry, a typo) must not
+	// leave a WAL record behind, since replaying a delete for an ID that's
+	// never re-inserted fails onDelete and bricks every future restart - see
+	// HandleUpsert, which checks Exists the same way before deciding whether
+	// to log anything.
+	if !h.bruteForce.Exists(req.ID) {
+		sendJSON(w, http.StatusNotFound, types.DeleteResponse{
+			Success: false,
+			Message: fmt.Sprintf("vector %q not found", req.ID),
+		})
+		return
+	}
+
+	// Log the delete before applying it, same as HandleInsert.
+	if h.wal != nil {
+		if _, err := h.wal.AppendDelete(req.ID); err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.DeleteResponse{
+				Success: false,
+				Message: "Failed to append to WAL: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.bruteForce.Delete(req.ID); err != nil {
+		sendJSON(w, http.StatusNotFound, types.DeleteResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := h.hnsw.Delete(req.ID); err != nil {
+		sendJSON(w, http.StatusInternalServerError, types.DeleteResponse{
+			Success: false,
+			Message: "Failed to delete from HNSW index: " + err.Error(),
+		})
+		return
+	}
+
+	h.maybeSnapshot()
+
+	sendJSON(w, http.StatusOK, types.DeleteResponse{
+		Success: true,
+		Message: "Vector deleted successfully",
+	})
+}
+
+// HandleDeleteBatch handles POST /delete/batch requests.
+func (h *Handler) HandleDeleteBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.DeleteBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSON(w, http.StatusBadRequest, types.DeleteBatchResponse{
+			Deleted: 0,
+			Message: "Invalid JSON: " + err.Error(),
+		})
+		return
+	}
+
+	if h.sharded != nil {
+		deleted := 0
+		for _, id := range req.IDs {
+			if err := h.sharded.Delete(id); err != nil {
+				sendJSON(w, http.StatusNotFound, types.DeleteBatchResponse{
+					Deleted: deleted,
+					Message: err.Error(),
+				})
+				return
+			}
+			deleted++
+		}
+		sendJSON(w, http.StatusOK, types.DeleteBatchResponse{
+			Deleted: deleted,
+			Message: "Vectors deleted successfully",
+		})
+		return
+	}
+
+	// Validate every ID exists before logging any of them to the WAL: same
+	// reasoning as HandleDelete - a batch containing even one already-gone
+	// ID must not leave a WAL record for it, or a replay of that record on a
+	// future restart fails onDelete and bricks the server. Checked as one
+	// all-or-nothing pass up front, the same way HandleInsertBatch validates
+	// every vector's embedding before inserting any of them.
+	for _, id := range req.IDs {
+		if !h.bruteForce.Exists(id) {
+			sendJSON(w, http.StatusNotFound, types.DeleteBatchResponse{
+				Deleted: 0,
+				Message: fmt.Sprintf("vector %q not found", id),
+			})
+			return
+		}
+	}
+
+	// Log every ID in the batch before applying any of it, one record per
+	// ID, same as HandleInsertBatch.
+	if h.wal != nil {
+		for _, id := range req.IDs {
+			if _, err := h.wal.AppendDelete(id); err != nil {
+				sendJSON(w, http.StatusInternalServerError, types.DeleteBatchResponse{
+					Deleted: 0,
+					Message: "Failed to append to WAL: " + err.Error(),
+				})
+				return
+			}
+		}
+	}
+
+	deletedBF, err := h.bruteForce.DeleteBatch(req.IDs)
+	if err != nil {
+		sendJSON(w, http.StatusNotFound, types.DeleteBatchResponse{
+			Deleted: deletedBF,
+			Message: err.Error(),
+		})
+		return
+	}
+	if _, err := h.hnsw.DeleteBatch(req.IDs); err != nil {
+		sendJSON(w, http.StatusInternalServerError, types.DeleteBatchResponse{
+			Deleted: deletedBF,
+			Message: "Failed to delete from HNSW index: " + err.Error(),
+		})
+		return
+	}
+
+	h.maybeSnapshot()
+
+	sendJSON(w, http.StatusOK, types.DeleteBatchResponse{
+		Deleted: deletedBF,
+		Message: "Vectors deleted successfully",
+	})
+}
+
+// HandleUpsert handles POST /upsert requests: inserts the vector, or
+// replaces it in place if its ID already exists.
+func (h *Handler) HandleUpsert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.UpsertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSON(w, http.StatusBadRequest, types.UpsertResponse{
+			Success: false,
+			Message: "Invalid JSON: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.Embedding) != h.dimensions {
+		sendJSON(w, http.StatusBadRequest, types.UpsertResponse{
+			Success: false,
+			Message: "Invalid embedding dimensions",
+		})
+		return
+	}
+
+	if req.Metric != "" && req.Metric != h.metric.Name() {
+		sendJSON(w, http.StatusBadRequest, types.UpsertResponse{
+			Success: false,
+			Message: "Metric mismatch: index uses \"" + h.metric.Name() + "\", got \"" + req.Metric + "\"",
+		})
+		return
+	}
+
+	v := types.Vector{
+		ID:        req.ID,
+		Embedding: req.Embedding,
+		Metadata:  req.Metadata,
+	}
+
+	if h.sharded != nil {
+		created := !h.sharded.Exists(v.ID)
+		if err := h.sharded.Upsert(v); err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.UpsertResponse{
+				Success: false,
+				Message: "Failed to upsert into sharded index: " + err.Error(),
+			})
+			return
+		}
+		sendJSON(w, http.StatusOK, types.UpsertResponse{
+			Success: true,
+			Created: created,
+			Message: "Vector upserted successfully",
+		})
+		return
+	}
+
+	// Whether the ID already existed decides what the WAL needs: replaying
+	// an upsert of an existing ID has to first delete the old copy, or
+	// BruteForceIndex.Insert would leave a stale duplicate entry behind
+	// alongside the new one.
+	created := !h.bruteForce.Exists(v.ID)
+
+	if h.wal != nil {
+		if !created {
+			if _, err := h.wal.AppendDelete(v.ID); err != nil {
+				sendJSON(w, http.StatusInternalServerError, types.UpsertResponse{
+					Success: false,
+					Message: "Failed to append to WAL: " + err.Error(),
+				})
+				return
+			}
+		}
+		if _, err := h.wal.AppendInsert(v); err != nil {
+			sendJSON(w, http.StatusInternalServerError, types.UpsertResponse{
+				Success: false,
+				Message: "Failed to append to WAL: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.bruteForce.Upsert(v); err != nil {
+		sendJSON(w, http.StatusInternalServerError, types.UpsertResponse{
+			Success: false,
+			Message: "Failed to upsert into brute-force index: " + err.Error(),
+		})
+		return
+	}
+	if err := h.hnsw.Upsert(v); err != nil {
+		sendJSON(w, http.StatusInternalServerError, types.UpsertResponse{
+			Success: false,
+			Message: "Failed to upsert into HNSW index: " + err.Error(),
+		})
+		return
+	}
+
+	h.maybeSnapshot()
+
+	sendJSON(w, http.StatusOK, types.UpsertResponse{
+		Success: true,
+		Created: created,
+		Message: "Vector upserted successfully",
+	})
+}
+
 // HandleSearch handles POST /search requests.
 func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -152,24 +782,96 @@ func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Metric != "" && req.Metric != h.metric.Name() {
+		sendJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Metric mismatch: index uses \"" + h.metric.Name() + "\", got \"" + req.Metric + "\"",
+		})
+		return
+	}
+
 	if req.TopK <= 0 {
 		req.TopK = 10 // Default
 	}
 
+	// ?index=hnsw-pq bypasses the Algorithm switch below and searches the
+	// PQ-compressed index directly; see the matching branch in HandleInsert.
+	if r.URL.Query().Get("index") == "hnsw-pq" {
+		h.pqMu.RLock()
+		hnswPQ := h.hnswPQ
+		h.pqMu.RUnlock()
+		if hnswPQ == nil {
+			sendJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "hnsw-pq index has not been trained yet; call POST /index/pq/train first",
+			})
+			return
+		}
+		start := time.Now()
+		results := hnswPQ.Search(req.Embedding, req.TopK)
+		sendJSON(w, http.StatusOK, types.SearchResponse{
+			Results: results,
+			Latency: float64(time.Since(start).Microseconds()) / 1000.0,
+		})
+		return
+	}
+
+	// Sharded mode scatters the query across whichever shards req.Filter
+	// could match and merges the results, bypassing the Algorithm switch
+	// below entirely - see index.ShardedIndex.Search.
+	if h.sharded != nil {
+		start := time.Now()
+		results := h.sharded.Search(req.Embedding, req.TopK, req.Filter)
+		sendJSON(w, http.StatusOK, types.SearchResponse{
+			Results: results,
+			Latency: float64(time.Since(start).Microseconds()) / 1000.0,
+		})
+		return
+	}
+
 	if req.Algorithm == "" {
 		req.Algorithm = "hnsw" // Default to HNSW
 	}
 
+	// A client that sends Accept: application/x-ndjson gets results streamed
+	// one JSON object per line from a ResultIterator, instead of marshaled as
+	// one SearchResponse. The full top-k is still ranked before the first
+	// line is written; the iterator holds it in pooled buffers rather than a
+	// freshly allocated slice. Only bruteforce/hnsw have a ResultIterator
+	// to stream from; other algorithms fall through to the normal response
+	// below regardless of the Accept header.
+	if r.Header.Get("Accept") == "application/x-ndjson" && (req.Algorithm == "bruteforce" || req.Algorithm == "hnsw") {
+		var it index.ResultIterator
+		if req.Algorithm == "bruteforce" {
+			it = h.bruteForce.SearchIterFiltered(req.Embedding, req.TopK, req.Filter)
+		} else {
+			it = h.hnsw.SearchIterFiltered(req.Embedding, req.TopK, req.Filter)
+		}
+		defer it.Close()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		for {
+			result, ok := it.Next()
+			if !ok {
+				break
+			}
+			if err := enc.Encode(result); err != nil {
+				return
+			}
+		}
+		return
+	}
+
 	start := time.Now()
 	var results []types.SearchResult
 
 	switch req.Algorithm {
 	case "bruteforce":
-		results = h.bruteForce.Search(req.Embedding, req.TopK)
+		results = h.bruteForce.SearchFiltered(req.Embedding, req.TopK, req.Filter)
 	case "bruteforce_concurrent":
-		results = h.bruteForce.SearchConcurrent(req.Embedding, req.TopK, 4)
+		results = h.bruteForce.SearchConcurrentFiltered(req.Embedding, req.TopK, 4, req.Filter)
 	case "hnsw":
-		results = h.hnsw.Search(req.Embedding, req.TopK)
+		results = h.hnsw.SearchFiltered(req.Embedding, req.TopK, req.Filter)
 	default:
 		sendJSON(w, http.StatusBadRequest, map[string]string{"error": "Unknown algorithm: " + req.Algorithm})
 		return
@@ -183,6 +885,113 @@ func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleTrainPQ handles POST /index/pq/train requests: trains a Product
+// Quantization codec on the given sample corpus and builds a fresh,
+// empty hnsw-pq index from it. Training replaces any previously trained
+// codec; vectors inserted into the old hnsw-pq index are not migrated.
+func (h *Handler) HandleTrainPQ(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.TrainPQRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSON(w, http.StatusBadRequest, types.TrainPQResponse{
+			Success: false,
+			Message: "Invalid JSON: " + err.Error(),
+		})
+		return
+	}
+
+	for _, v := range req.Vectors {
+		if len(v) != h.dimensions {
+			sendJSON(w, http.StatusBadRequest, types.TrainPQResponse{
+				Success: false,
+				Message: "Invalid embedding dimensions in training corpus",
+			})
+			return
+		}
+	}
+
+	m, k, iters := req.M, req.K, req.Iterations
+	if m <= 0 {
+		m = 8
+	}
+	if k <= 0 {
+		k = 256
+	}
+	if iters <= 0 {
+		iters = 25
+	}
+
+	codec, err := quantize.NewPQ(h.dimensions, m, k)
+	if err != nil {
+		sendJSON(w, http.StatusBadRequest, types.TrainPQResponse{
+			Success: false,
+			Message: "Failed to create codec: " + err.Error(),
+		})
+		return
+	}
+	if err := codec.Train(req.Vectors, iters); err != nil {
+		sendJSON(w, http.StatusBadRequest, types.TrainPQResponse{
+			Success: false,
+			Message: "Failed to train codec: " + err.Error(),
+		})
+		return
+	}
+
+	hnswConfig := index.DefaultHNSWConfig(h.dimensions)
+	hnswPQ, err := index.NewHNSWPQIndex(hnswConfig, codec)
+	if err != nil {
+		sendJSON(w, http.StatusInternalServerError, types.TrainPQResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to build hnsw-pq index: %v", err),
+		})
+		return
+	}
+
+	h.pqMu.Lock()
+	h.pqCodec = codec
+	h.hnswPQ = hnswPQ
+	h.pqMu.Unlock()
+
+	sendJSON(w, http.StatusOK, types.TrainPQResponse{
+		Success:  true,
+		Message:  "PQ codec trained successfully",
+		CodeSize: codec.CodeSize(),
+	})
+}
+
+// HandleSnapshot handles POST /snapshot requests, forcing an immediate
+// snapshot-and-truncate regardless of the configured size threshold.
+func (h *Handler) HandleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.wal == nil {
+		sendJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "WAL is not enabled; start the server with --wal-dir to use snapshots",
+		})
+		return
+	}
+
+	seq, err := h.snapshot()
+	if err != nil {
+		sendJSON(w, http.StatusInternalServerError, map[string]string{
+			"error": "Failed to write snapshot: " + err.Error(),
+		})
+		return
+	}
+
+	sendJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"seq":     seq,
+	})
+}
+
 // HandleHealth handles GET /health requests.
 func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, http.StatusOK, types.HealthResponse{
@@ -193,11 +1002,45 @@ func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 
 // HandleStats handles GET /stats requests.
 func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
-	sendJSON(w, http.StatusOK, types.StatsResponse{
-		VectorCount: h.bruteForce.Count(),
-		Dimensions:  h.dimensions,
-		IndexType:   "hnsw+bruteforce",
-	})
+	stats := types.StatsResponse{
+		VectorCount:    h.bruteForce.Count(),
+		Dimensions:     h.dimensions,
+		IndexType:      "hnsw+bruteforce",
+		TombstoneCount: h.bruteForce.TombstoneCount(),
+	}
+
+	if h.sharded != nil {
+		stats.VectorCount = h.sharded.Count()
+		stats.IndexType = fmt.Sprintf("sharded-hnsw+bruteforce(%d)", h.sharded.NumShards())
+		stats.TombstoneCount = h.sharded.TombstoneCount()
+		for _, s := range h.sharded.Stats() {
+			stats.Shards = append(stats.Shards, types.ShardStats{
+				VectorCount:  s.VectorCount,
+				P50LatencyMs: float64(s.P50Latency.Microseconds()) / 1000.0,
+				P99LatencyMs: float64(s.P99Latency.Microseconds()) / 1000.0,
+				MemoryBytes:  s.MemoryBytes,
+			})
+		}
+	}
+
+	h.pqMu.RLock()
+	hnswPQ := h.hnswPQ
+	h.pqMu.RUnlock()
+	if hnswPQ != nil {
+		stats.PQBytesPerVector, stats.UncompressedBytesPerVector = hnswPQ.MemoryPerVector()
+	}
+
+	if h.wal != nil {
+		if size, err := h.wal.Size(); err == nil {
+			stats.WALSizeBytes = size
+		}
+		h.snapMu.Lock()
+		stats.LastSnapshotSeq = h.lastSnapshotSeq
+		h.snapMu.Unlock()
+		stats.ReplayDurationMs = h.replayDurationMs
+	}
+
+	sendJSON(w, http.StatusOK, stats)
 }
 
 // sendJSON sends a JSON response with the given status code.
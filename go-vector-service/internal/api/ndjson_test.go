@@ -0,0 +1,59 @@
+// Tests for HandleSearch's NDJSON streaming path.
+package api_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/documind/vector-service/internal/api"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestHandleSearchStreamsNDJSONWhenRequested checks that an Accept:
+// application/x-ndjson request gets one JSON object per line instead of a
+// single SearchResponse array, and that the results match what a regular
+// JSON request would have returned.
+func TestHandleSearchStreamsNDJSONWhenRequested(t *testing.T) {
+	h, err := api.NewHandler(3)
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+	doInsert(t, h, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	doInsert(t, h, types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+	doInsert(t, h, types.Vector{ID: "c", Embedding: []float32{0.9, 0.1, 0}})
+
+	body := mustMarshal(t, types.SearchRequest{Embedding: []float32{1, 0, 0}, TopK: 2, Algorithm: "bruteforce"})
+	req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(body))
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	h.HandleSearch(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected Content-Type application/x-ndjson, got %q", ct)
+	}
+
+	var results []types.SearchResult
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		var r types.SearchResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("unmarshal line %q: %v", scanner.Text(), err)
+		}
+		results = append(results, r)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 streamed results, got %d: %+v", len(results), results)
+	}
+	if results[0].ID != "a" {
+		t.Errorf("expected first streamed result to be 'a', got %q", results[0].ID)
+	}
+}
@@ -45,7 +45,12 @@ func NewRouter(handler *Handler) *mux.Router {
 	// Register routes
 	r.HandleFunc("/insert", handler.HandleInsert).Methods("POST", "OPTIONS")
 	r.HandleFunc("/insert/batch", handler.HandleInsertBatch).Methods("POST", "OPTIONS")
+	r.HandleFunc("/delete", handler.HandleDelete).Methods("POST", "OPTIONS")
+	r.HandleFunc("/delete/batch", handler.HandleDeleteBatch).Methods("POST", "OPTIONS")
+	r.HandleFunc("/upsert", handler.HandleUpsert).Methods("POST", "OPTIONS")
 	r.HandleFunc("/search", handler.HandleSearch).Methods("POST", "OPTIONS")
+	r.HandleFunc("/index/pq/train", handler.HandleTrainPQ).Methods("POST", "OPTIONS")
+	r.HandleFunc("/snapshot", handler.HandleSnapshot).Methods("POST", "OPTIONS")
 	r.HandleFunc("/health", handler.HandleHealth).Methods("GET")
 	r.HandleFunc("/stats", handler.HandleStats).Methods("GET")
 
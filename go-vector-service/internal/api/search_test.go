@@ -0,0 +1,46 @@
+// Tests for HandleSearch's algorithm dispatch.
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/documind/vector-service/internal/api"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestHandleSearchBruteForceConcurrentHonorsFilter checks that the
+// "bruteforce_concurrent" algorithm scopes results by req.Filter the same
+// way "bruteforce" and "hnsw" do, instead of silently searching the whole
+// corpus.
+func TestHandleSearchBruteForceConcurrentHonorsFilter(t *testing.T) {
+	h, err := api.NewHandler(3)
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+	doInsert(t, h, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}, Metadata: types.Metadata{DocumentID: "doc-1"}})
+	doInsert(t, h, types.Vector{ID: "b", Embedding: []float32{0.99, 0.01, 0}, Metadata: types.Metadata{DocumentID: "doc-2"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(mustMarshal(t, types.SearchRequest{
+		Embedding: []float32{1, 0, 0},
+		TopK:      10,
+		Algorithm: "bruteforce_concurrent",
+		Filter:    &types.Filter{DocumentID: "doc-1"},
+	})))
+	rec := httptest.NewRecorder()
+	h.HandleSearch(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp types.SearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal search response: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ID != "a" {
+		t.Fatalf("expected filter to scope bruteforce_concurrent results to just 'a', got %+v", resp.Results)
+	}
+}
@@ -0,0 +1,66 @@
+// Tests for WithNumShards-backed handlers.
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/documind/vector-service/internal/api"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestShardedHandlerInsertAndSearch checks that a Handler built with
+// WithNumShards still accepts inserts and finds them again via search,
+// routing through index.ShardedIndex instead of the single bruteForce/hnsw
+// pair.
+func TestShardedHandlerInsertAndSearch(t *testing.T) {
+	h, err := api.NewHandler(3, api.WithNumShards(4))
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+
+	doInsert(t, h, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}, Metadata: types.Metadata{TenantID: "acme"}})
+	doInsert(t, h, types.Vector{ID: "b", Embedding: []float32{0, 1, 0}, Metadata: types.Metadata{TenantID: "globex"}})
+
+	if got := vectorCount(t, h); got != 2 {
+		t.Fatalf("expected 2 vectors, got %d", got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(mustMarshal(t, types.SearchRequest{
+		Embedding: []float32{1, 0, 0},
+		TopK:      1,
+		Filter:    &types.Filter{TenantID: "acme"},
+	})))
+	rec := httptest.NewRecorder()
+	h.HandleSearch(rec, req)
+	var resp types.SearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal search response: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ID != "a" {
+		t.Fatalf("expected tenant-scoped search to find 'a', got %+v", resp.Results)
+	}
+
+	statsReq := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	statsRec := httptest.NewRecorder()
+	h.HandleStats(statsRec, statsReq)
+	var stats types.StatsResponse
+	if err := json.Unmarshal(statsRec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unmarshal stats: %v", err)
+	}
+	if len(stats.Shards) != 4 {
+		t.Errorf("expected 4 shard stats entries, got %d", len(stats.Shards))
+	}
+}
+
+// TestShardedHandlerRejectsWAL checks that NewHandler refuses to combine
+// WithNumShards > 1 with WithWALDir, since sharded mode has no WAL support.
+func TestShardedHandlerRejectsWAL(t *testing.T) {
+	_, err := api.NewHandler(3, api.WithNumShards(2), api.WithWALDir(t.TempDir()))
+	if err == nil {
+		t.Fatal("expected an error combining WithNumShards and WithWALDir, got nil")
+	}
+}
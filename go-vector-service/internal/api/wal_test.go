@@ -0,0 +1,191 @@
+// Tests for WAL-backed crash recovery: a handler's acknowledged writes must
+// survive an unclean restart.
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/documind/vector-service/internal/api"
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/internal/wal"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+func doInsert(t *testing.T, h *api.Handler, v types.Vector) {
+	t.Helper()
+	body, err := json.Marshal(types.InsertRequest{ID: v.ID, Embedding: v.Embedding, Metadata: v.Metadata})
+	if err != nil {
+		t.Fatalf("marshal insert request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/insert", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.HandleInsert(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("insert %q: expected 200, got %d: %s", v.ID, rec.Code, rec.Body.String())
+	}
+}
+
+func doInsertBatch(t *testing.T, h *api.Handler, vectors []types.Vector) {
+	t.Helper()
+	body, err := json.Marshal(types.InsertBatchRequest{Vectors: vectors})
+	if err != nil {
+		t.Fatalf("marshal batch insert request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/insert/batch", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.HandleInsertBatch(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("insert batch: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func vectorCount(t *testing.T, h *api.Handler) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	rec := httptest.NewRecorder()
+	h.HandleStats(rec, req)
+	var stats types.StatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unmarshal stats: %v", err)
+	}
+	return stats.VectorCount
+}
+
+// TestWALCrashRecoveryAcrossRestart checks that every vector acknowledged
+// by /insert or /insert/batch is still present after the service "crashes" -
+// simulated here by discarding the Handler without any graceful shutdown
+// step, since durability under the default FsyncAlways policy must not
+// depend on one - and a fresh Handler is brought up over the same
+// --wal-dir.
+func TestWALCrashRecoveryAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	h1, err := api.NewHandler(3, api.WithMetric(index.Cosine{}), api.WithWALDir(dir), api.WithFsyncPolicy(wal.FsyncAlways), api.WithSnapshotThresholdBytes(0))
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+
+	doInsert(t, h1, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	doInsert(t, h1, types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+	doInsertBatch(t, h1, []types.Vector{
+		{ID: "c", Embedding: []float32{0, 0, 1}},
+		{ID: "d", Embedding: []float32{1, 1, 0}},
+	})
+
+	if got := vectorCount(t, h1); got != 4 {
+		t.Fatalf("expected 4 vectors acknowledged before the crash, got %d", got)
+	}
+
+	// Simulate the crash: h1 is simply abandoned, with no Close call.
+
+	h2, err := api.NewHandler(3, api.WithMetric(index.Cosine{}), api.WithWALDir(dir), api.WithFsyncPolicy(wal.FsyncAlways), api.WithSnapshotThresholdBytes(0))
+	if err != nil {
+		t.Fatalf("NewHandler (restart) returned error: %v", err)
+	}
+
+	if got := vectorCount(t, h2); got != 4 {
+		t.Errorf("expected all 4 acknowledged vectors to survive the restart, got %d", got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(mustMarshal(t, types.SearchRequest{
+		Embedding: []float32{1, 0, 0},
+		TopK:      1,
+		Algorithm: "bruteforce",
+	})))
+	rec := httptest.NewRecorder()
+	h2.HandleSearch(rec, req)
+	var resp types.SearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal search response: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ID != "a" {
+		t.Errorf("expected recovered index to still find 'a', got %+v", resp.Results)
+	}
+}
+
+// TestWALSnapshotTruncatesAndSurvivesRestart checks that once a snapshot has
+// been taken (via POST /snapshot) and the WAL truncated, a restart still
+// recovers every vector - from the snapshot instead of the (now-empty) log.
+func TestWALSnapshotTruncatesAndSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	h1, err := api.NewHandler(2, api.WithMetric(index.Cosine{}), api.WithWALDir(dir), api.WithFsyncPolicy(wal.FsyncAlways), api.WithSnapshotThresholdBytes(0))
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+	doInsert(t, h1, types.Vector{ID: "a", Embedding: []float32{1, 0}})
+	doInsert(t, h1, types.Vector{ID: "b", Embedding: []float32{0, 1}})
+
+	req := httptest.NewRequest(http.MethodPost, "/snapshot", nil)
+	rec := httptest.NewRecorder()
+	h1.HandleSnapshot(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("snapshot: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	doInsert(t, h1, types.Vector{ID: "c", Embedding: []float32{1, 1}})
+
+	h2, err := api.NewHandler(2, api.WithMetric(index.Cosine{}), api.WithWALDir(dir), api.WithFsyncPolicy(wal.FsyncAlways), api.WithSnapshotThresholdBytes(0))
+	if err != nil {
+		t.Fatalf("NewHandler (restart) returned error: %v", err)
+	}
+	if got := vectorCount(t, h2); got != 3 {
+		t.Errorf("expected 3 vectors (2 from snapshot + 1 from the post-snapshot WAL), got %d", got)
+	}
+
+	statsReq := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	statsRec := httptest.NewRecorder()
+	h2.HandleStats(statsRec, statsReq)
+	var stats types.StatsResponse
+	if err := json.Unmarshal(statsRec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unmarshal stats: %v", err)
+	}
+	if stats.LastSnapshotSeq == 0 {
+		t.Error("expected LastSnapshotSeq to be populated after a snapshot")
+	}
+}
+
+// TestWALDeleteOfMissingIDDoesNotBrickRestart checks that deleting an ID
+// that was never inserted (or already deleted) doesn't append a WAL record -
+// if it did, a later restart would replay that record, call onDelete for an
+// ID the index doesn't have, and fail NewHandler every time afterward, with
+// no recovery short of editing the WAL file by hand.
+func TestWALDeleteOfMissingIDDoesNotBrickRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	h1, err := api.NewHandler(3, api.WithMetric(index.Cosine{}), api.WithWALDir(dir), api.WithFsyncPolicy(wal.FsyncAlways), api.WithSnapshotThresholdBytes(0))
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+	doInsert(t, h1, types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+
+	req := httptest.NewRequest(http.MethodPost, "/delete", bytes.NewReader(mustMarshal(t, types.DeleteRequest{ID: "nonexistent"})))
+	rec := httptest.NewRecorder()
+	h1.HandleDelete(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting a nonexistent vector, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Simulate the crash: h1 is simply abandoned, with no Close call.
+
+	h2, err := api.NewHandler(3, api.WithMetric(index.Cosine{}), api.WithWALDir(dir), api.WithFsyncPolicy(wal.FsyncAlways), api.WithSnapshotThresholdBytes(0))
+	if err != nil {
+		t.Fatalf("NewHandler (restart) returned error: %v - a 404 delete must not have been logged to the WAL", err)
+	}
+	if got := vectorCount(t, h2); got != 1 {
+		t.Errorf("expected 'a' to survive the restart, got %d vectors", got)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return data
+}
@@ -0,0 +1,42 @@
+// Tests for BatchDistance and BruteForceIndex.Search's use of it.
+package index_test
+
+import (
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestBruteForceSearchBatchScoringMatchesPerPairScoring checks that a
+// cosine-metric index, which scores candidates through BatchDistance, ranks
+// them in the order per-pair cosine similarity gives: a (1), c (~0.97),
+// b (0), d (-1).
+func TestBruteForceSearchBatchScoringMatchesPerPairScoring(t *testing.T) {
+	vectors := []types.Vector{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "b", Embedding: []float32{0, 1, 0}},
+		{ID: "c", Embedding: []float32{0.8, 0.2, 0}},
+		{ID: "d", Embedding: []float32{-1, 0, 0}},
+	}
+	query := []float32{1, 0, 0}
+
+	var _ index.BatchDistance = index.Cosine{} // Cosine must implement the fast path.
+
+	idx := index.NewBruteForceIndex(3)
+	for _, v := range vectors {
+		idx.Insert(v)
+	}
+
+	results := idx.Search(query, len(vectors))
+	if len(results) != len(vectors) {
+		t.Fatalf("expected %d results, got %d", len(vectors), len(results))
+	}
+	want := []string{"a", "c", "b", "d"}
+	for i, id := range want {
+		if results[i].ID != id {
+			t.Errorf("rank %d: got %q, want %q (full: %+v)", i, results[i].ID, id, results)
+			break
+		}
+	}
+}
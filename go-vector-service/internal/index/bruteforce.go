@@ -3,28 +3,55 @@ package index
 
 import (
 	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
 	"sync"
 
 	"github.com/documind/vector-service/pkg/types"
 )
 
+// bruteForceCompactionThreshold is the fraction of tombstoned (deleted but
+// not yet physically removed) entries in idx.vectors that triggers a
+// compaction pass. See Delete and compactLocked.
+const bruteForceCompactionThreshold = 0.3
+
 // BruteForceIndex implements exact nearest neighbor search using linear scan.
 // This is the baseline implementation with O(n) search complexity.
 // Use this for correctness verification and small datasets.
 type BruteForceIndex struct {
 	vectors    []types.Vector
+	deleted    []bool // deleted[i] tombstones vectors[i]; see Delete
+	deadCount  int    // number of deleted entries, kept so Delete doesn't rescan
 	dimensions int
+	metric     Distance
+	tagIndex   *invertedIndex
 	mu         sync.RWMutex
 }
 
-// NewBruteForceIndex creates a new brute-force index.
+// NewBruteForceIndex creates a new brute-force index using cosine
+// similarity. Use NewBruteForceIndexWithMetric for any other metric.
 func NewBruteForceIndex(dimensions int) *BruteForceIndex {
+	return NewBruteForceIndexWithMetric(dimensions, Cosine{})
+}
+
+// NewBruteForceIndexWithMetric creates a new brute-force index scored with
+// the given Distance instead of the default cosine similarity.
+func NewBruteForceIndexWithMetric(dimensions int, metric Distance) *BruteForceIndex {
 	return &BruteForceIndex{
 		vectors:    make([]types.Vector, 0),
+		deleted:    make([]bool, 0),
 		dimensions: dimensions,
+		metric:     metric,
+		tagIndex:   newInvertedIndex(),
 	}
 }
 
+// Metric returns the Distance this index scores results with.
+func (idx *BruteForceIndex) Metric() Distance {
+	return idx.metric
+}
+
 // Insert adds a vector to the index.
 // Thread-safe with write lock.
 func (idx *BruteForceIndex) Insert(v types.Vector) error {
@@ -32,6 +59,8 @@ func (idx *BruteForceIndex) Insert(v types.Vector) error {
 	defer idx.mu.Unlock()
 
 	idx.vectors = append(idx.vectors, v)
+	idx.deleted = append(idx.deleted, false)
+	idx.tagIndex.insert(v.ID, v.Metadata)
 	return nil
 }
 
@@ -41,13 +70,139 @@ func (idx *BruteForceIndex) InsertBatch(vectors []types.Vector) (int, error) {
 	defer idx.mu.Unlock()
 
 	idx.vectors = append(idx.vectors, vectors...)
+	for _, v := range vectors {
+		idx.deleted = append(idx.deleted, false)
+		idx.tagIndex.insert(v.ID, v.Metadata)
+	}
 	return len(vectors), nil
 }
 
-// Search finds the top-k most similar vectors using cosine similarity.
+// Delete tombstones the vector with the given ID instead of removing it from
+// idx.vectors outright, so a delete under heavy write load doesn't pay for a
+// slice shift on every call. Tombstoned entries are skipped by
+// Search/SearchFiltered/Exists/Count as if already gone, and physically
+// dropped once compactLocked runs - see bruteForceCompactionThreshold.
+func (idx *BruteForceIndex) Delete(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for i, v := range idx.vectors {
+		if v.ID == id && !idx.deleted[i] {
+			idx.deleted[i] = true
+			idx.deadCount++
+			idx.tagIndex.remove(id, v.Metadata)
+			idx.compactLocked()
+			return nil
+		}
+	}
+	return fmt.Errorf("bruteforce: vector %q not found", id)
+}
+
+// compactLocked physically drops every tombstoned entry once they make up
+// more than bruteForceCompactionThreshold of idx.vectors. The caller must
+// already hold idx.mu for writing.
+func (idx *BruteForceIndex) compactLocked() {
+	if idx.deadCount == 0 || float64(idx.deadCount)/float64(len(idx.vectors)) <= bruteForceCompactionThreshold {
+		return
+	}
+
+	live := make([]types.Vector, 0, len(idx.vectors)-idx.deadCount)
+	liveDeleted := make([]bool, 0, cap(live))
+	for i, v := range idx.vectors {
+		if idx.deleted[i] {
+			continue
+		}
+		live = append(live, v)
+		liveDeleted = append(liveDeleted, false)
+	}
+	idx.vectors = live
+	idx.deleted = liveDeleted
+	idx.deadCount = 0
+}
+
+// Exists reports whether a vector with the given ID is currently in the
+// index.
+func (idx *BruteForceIndex) Exists(id string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for i, v := range idx.vectors {
+		if v.ID == id && !idx.deleted[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// TombstoneCount returns the number of entries that have been deleted but
+// not yet physically compacted out of the index.
+func (idx *BruteForceIndex) TombstoneCount() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.deadCount
+}
+
+// DeleteBatch removes each of ids from the index, stopping at the first one
+// not found. Returns how many were removed before that.
+func (idx *BruteForceIndex) DeleteBatch(ids []string) (int, error) {
+	for i, id := range ids {
+		if err := idx.Delete(id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// Upsert replaces the vector with v.ID in place if it already exists, or
+// appends v as new otherwise. Replacing in place (rather than calling
+// Delete then Insert) avoids leaving a hole in idx.vectors that the
+// following append would just have to fill back in. A tombstoned entry for
+// v.ID is revived into the same slot instead, rather than left dead
+// alongside a freshly appended one.
+func (idx *BruteForceIndex) Upsert(v types.Vector) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for i, existing := range idx.vectors {
+		if existing.ID != v.ID {
+			continue
+		}
+		if idx.deleted[i] {
+			idx.deleted[i] = false
+			idx.deadCount--
+		} else {
+			idx.tagIndex.remove(v.ID, existing.Metadata)
+		}
+		idx.vectors[i] = v
+		idx.tagIndex.insert(v.ID, v.Metadata)
+		return nil
+	}
+	idx.vectors = append(idx.vectors, v)
+	idx.deleted = append(idx.deleted, false)
+	idx.tagIndex.insert(v.ID, v.Metadata)
+	return nil
+}
+
+// Search finds the top-k most similar vectors using the index's configured
+// Distance metric.
 // Uses a min-heap to efficiently track top-k results.
 // Time Complexity: O(n * d + n * log(k)) where n=vectors, d=dimensions, k=topK
 func (idx *BruteForceIndex) Search(query []float32, topK int) []types.SearchResult {
+	return idx.SearchFiltered(query, topK, nil)
+}
+
+// SearchFiltered is Search scoped to vectors whose Metadata satisfies
+// filter. A nil or zero-value filter behaves exactly like Search. The
+// predicate is evaluated before computing similarity, and when filter sets
+// an equality clause the inverted tag index's matching IDs let the scan
+// skip every other vector before the full predicate runs; the scan itself
+// still walks every entry.
+//
+// Scoring itself goes through scoreAll, which batches the call to the
+// configured metric when it supports it (see BatchDistance) so the query's
+// own load - e.g. its norm, for cosine - is paid once instead of once per
+// candidate.
+func (idx *BruteForceIndex) SearchFiltered(query []float32, topK int, filter *types.Filter) []types.SearchResult {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
@@ -55,14 +210,32 @@ func (idx *BruteForceIndex) Search(query []float32, topK int) []types.SearchResu
 		return []types.SearchResult{}
 	}
 
+	candidateIDs, scoped := idx.tagIndex.candidateIDs(filter)
+
+	matched := make([]types.Vector, 0, len(idx.vectors))
+	for i, v := range idx.vectors {
+		if idx.deleted[i] {
+			continue
+		}
+		if scoped && !candidateIDs[v.ID] {
+			continue
+		}
+		if !filter.Matches(v.Metadata) {
+			continue
+		}
+		matched = append(matched, v)
+	}
+
+	scores := idx.scoreAll(query, matched)
+
 	// Use a min-heap to track top-k results
 	// We use a min-heap so we can efficiently remove the smallest score
 	// when we find a better candidate
 	h := &resultHeap{}
 	heap.Init(h)
 
-	for _, v := range idx.vectors {
-		score := CosineSimilarity(query, v.Embedding)
+	for i, v := range matched {
+		score := scores[i]
 
 		if h.Len() < topK {
 			heap.Push(h, types.SearchResult{
@@ -90,10 +263,87 @@ func (idx *BruteForceIndex) Search(query []float32, topK int) []types.SearchResu
 	return results
 }
 
+// scoreAll returns idx.metric's similarity score for query against each of
+// vectors, in order. When idx.metric implements BatchDistance, it's scored
+// in a single batched call; otherwise Similarity is called once per vector.
+func (idx *BruteForceIndex) scoreAll(query []float32, vectors []types.Vector) []float32 {
+	scores := make([]float32, len(vectors))
+	if batch, ok := idx.metric.(BatchDistance); ok {
+		corpus := make([][]float32, len(vectors))
+		for i, v := range vectors {
+			corpus[i] = v.Embedding
+		}
+		batch.SimilarityBatch(query, corpus, scores)
+		return scores
+	}
+	for i, v := range vectors {
+		scores[i] = idx.metric.Similarity(query, v.Embedding)
+	}
+	return scores
+}
+
+// SearchIter is Search, but returns a ResultIterator backed by pooled
+// buffers instead of a freshly allocated slice - see SearchIterFiltered.
+func (idx *BruteForceIndex) SearchIter(query []float32, topK int) ResultIterator {
+	return idx.SearchIterFiltered(query, topK, nil)
+}
+
+// SearchIterFiltered is SearchFiltered, but scores into a resultHeap drawn
+// from resultHeapPool and drains it into a scratch slice drawn from
+// resultSlicePool, rather than allocating both fresh on every call. Callers
+// that want to stream results out (e.g. HandleSearch's NDJSON path) should
+// use this instead of SearchFiltered to avoid materializing the whole
+// result set as a second allocation before writing any of it.
+func (idx *BruteForceIndex) SearchIterFiltered(query []float32, topK int, filter *types.Filter) ResultIterator {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	h := resultHeapPool.Get().(*resultHeap)
+	*h = (*h)[:0]
+
+	if len(idx.vectors) > 0 {
+		candidateIDs, scoped := idx.tagIndex.candidateIDs(filter)
+
+		matched := make([]types.Vector, 0, len(idx.vectors))
+		for i, v := range idx.vectors {
+			if idx.deleted[i] {
+				continue
+			}
+			if scoped && !candidateIDs[v.ID] {
+				continue
+			}
+			if !filter.Matches(v.Metadata) {
+				continue
+			}
+			matched = append(matched, v)
+		}
+
+		scores := idx.scoreAll(query, matched)
+		for i, v := range matched {
+			score := scores[i]
+			if h.Len() < topK {
+				heap.Push(h, types.SearchResult{ID: v.ID, Score: score, Metadata: v.Metadata})
+			} else if score > (*h)[0].Score {
+				heap.Pop(h)
+				heap.Push(h, types.SearchResult{ID: v.ID, Score: score, Metadata: v.Metadata})
+			}
+		}
+	}
+
+	return drainHeapPooled(h)
+}
+
 // SearchConcurrent performs parallel search using goroutines.
 // Splits the vector space into chunks and searches in parallel.
 // This demonstrates Go's concurrency model.
 func (idx *BruteForceIndex) SearchConcurrent(query []float32, topK int, numWorkers int) []types.SearchResult {
+	return idx.SearchConcurrentFiltered(query, topK, numWorkers, nil)
+}
+
+// SearchConcurrentFiltered is SearchConcurrent with the same candidate/tag
+// filtering SearchFiltered applies, pre-filtering the live set once up front
+// so every worker only ever chunks and scores vectors that already match.
+func (idx *BruteForceIndex) SearchConcurrentFiltered(query []float32, topK int, numWorkers int, filter *types.Filter) []types.SearchResult {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
@@ -101,12 +351,31 @@ func (idx *BruteForceIndex) SearchConcurrent(query []float32, topK int, numWorke
 		return []types.SearchResult{}
 	}
 
+	candidateIDs, scoped := idx.tagIndex.candidateIDs(filter)
+
+	live := make([]types.Vector, 0, len(idx.vectors)-idx.deadCount)
+	for i, v := range idx.vectors {
+		if idx.deleted[i] {
+			continue
+		}
+		if scoped && !candidateIDs[v.ID] {
+			continue
+		}
+		if !filter.Matches(v.Metadata) {
+			continue
+		}
+		live = append(live, v)
+	}
+	if len(live) == 0 {
+		return []types.SearchResult{}
+	}
+
 	if numWorkers <= 0 {
 		numWorkers = 4
 	}
 
 	// Calculate chunk size for each worker
-	chunkSize := (len(idx.vectors) + numWorkers - 1) / numWorkers
+	chunkSize := (len(live) + numWorkers - 1) / numWorkers
 
 	// Channel to collect results from workers
 	resultsChan := make(chan []types.SearchResult, numWorkers)
@@ -117,19 +386,19 @@ func (idx *BruteForceIndex) SearchConcurrent(query []float32, topK int, numWorke
 	for i := 0; i < numWorkers; i++ {
 		start := i * chunkSize
 		end := start + chunkSize
-		if end > len(idx.vectors) {
-			end = len(idx.vectors)
+		if end > len(live) {
+			end = len(live)
 		}
-		if start >= len(idx.vectors) {
+		if start >= len(live) {
 			break
 		}
 
 		wg.Add(1)
 		go func(vectors []types.Vector) {
 			defer wg.Done()
-			results := searchChunk(query, vectors, topK)
+			results := searchChunk(query, vectors, topK, idx.metric)
 			resultsChan <- results
-		}(idx.vectors[start:end])
+		}(live[start:end])
 	}
 
 	// Close channel when all workers complete
@@ -143,12 +412,12 @@ func (idx *BruteForceIndex) SearchConcurrent(query []float32, topK int, numWorke
 }
 
 // searchChunk searches a subset of vectors and returns top-k results.
-func searchChunk(query []float32, vectors []types.Vector, topK int) []types.SearchResult {
+func searchChunk(query []float32, vectors []types.Vector, topK int, metric Distance) []types.SearchResult {
 	h := &resultHeap{}
 	heap.Init(h)
 
 	for _, v := range vectors {
-		score := CosineSimilarity(query, v.Embedding)
+		score := metric.Similarity(query, v.Embedding)
 
 		if h.Len() < topK {
 			heap.Push(h, types.SearchResult{
@@ -198,11 +467,11 @@ func mergeResults(resultsChan <-chan []types.SearchResult, topK int) []types.Sea
 	return results
 }
 
-// Count returns the number of vectors in the index.
+// Count returns the number of live (non-tombstoned) vectors in the index.
 func (idx *BruteForceIndex) Count() int {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
-	return len(idx.vectors)
+	return len(idx.vectors) - idx.deadCount
 }
 
 // Dimensions returns the dimensionality of vectors in the index.
@@ -210,6 +479,69 @@ func (idx *BruteForceIndex) Dimensions() int {
 	return idx.dimensions
 }
 
+// bruteForceSnapshotVersion is bumped whenever the on-disk layout of
+// bruteForceSnapshot changes, so Load can refuse snapshots it doesn't know
+// how to read.
+const bruteForceSnapshotVersion = 1
+
+// bruteForceSnapshot is the gob-serializable representation of a
+// BruteForceIndex.
+type bruteForceSnapshot struct {
+	Version    int
+	Dimensions int
+	Vectors    []types.Vector
+}
+
+// Save serializes the index's live (non-tombstoned) vectors to w using gob,
+// behind a versioned header so Load can reject snapshots written by an
+// incompatible version.
+func (idx *BruteForceIndex) Save(w io.Writer) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	vectors := make([]types.Vector, 0, len(idx.vectors)-idx.deadCount)
+	for i, v := range idx.vectors {
+		if !idx.deleted[i] {
+			vectors = append(vectors, v)
+		}
+	}
+	snap := bruteForceSnapshot{
+		Version:    bruteForceSnapshotVersion,
+		Dimensions: idx.dimensions,
+		Vectors:    vectors,
+	}
+	if err := gob.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("bruteforce: encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the index's contents with a snapshot previously written by
+// Save. Callers typically do this once at startup before serving traffic.
+func (idx *BruteForceIndex) Load(r io.Reader) error {
+	var snap bruteForceSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("bruteforce: decode snapshot: %w", err)
+	}
+	if snap.Version != bruteForceSnapshotVersion {
+		return fmt.Errorf("bruteforce: unsupported snapshot version %d (want %d)", snap.Version, bruteForceSnapshotVersion)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.dimensions = snap.Dimensions
+	idx.vectors = snap.Vectors
+	idx.deleted = make([]bool, len(snap.Vectors))
+	idx.deadCount = 0
+
+	idx.tagIndex = newInvertedIndex()
+	for _, v := range idx.vectors {
+		idx.tagIndex.insert(v.ID, v.Metadata)
+	}
+	return nil
+}
+
 // resultHeap is a min-heap for SearchResult, ordered by Score.
 // We use a min-heap so we can efficiently evict the lowest score
 // when we find a better candidate.
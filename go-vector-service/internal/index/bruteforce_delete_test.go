@@ -0,0 +1,54 @@
+// Tests for BruteForceIndex.Delete's tombstone-then-compact behavior.
+package index_test
+
+import (
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestBruteForceDeleteTombstonesUntilCompaction verifies a deleted vector is
+// reported as gone (Exists, Count, Search) immediately, but that
+// TombstoneCount only drops back to zero once enough deletes accumulate to
+// cross bruteForceCompactionThreshold and physically compact the index.
+func TestBruteForceDeleteTombstonesUntilCompaction(t *testing.T) {
+	idx := index.NewBruteForceIndex(3)
+	idx.InsertBatch([]types.Vector{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "b", Embedding: []float32{0, 1, 0}},
+		{ID: "c", Embedding: []float32{0, 0, 1}},
+		{ID: "d", Embedding: []float32{1, 1, 0}},
+	})
+
+	if err := idx.Delete("a"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if idx.Exists("a") {
+		t.Error("expected 'a' to be gone immediately after Delete")
+	}
+	if got := idx.Count(); got != 3 {
+		t.Fatalf("expected Count to exclude the tombstoned vector immediately, got %d", got)
+	}
+	for _, r := range idx.Search([]float32{1, 0, 0}, 4) {
+		if r.ID == "a" {
+			t.Errorf("deleted vector 'a' still reachable from search")
+		}
+	}
+	if got := idx.TombstoneCount(); got != 1 {
+		t.Fatalf("expected 1 pending tombstone below the compaction threshold (1/4 = 25%%), got %d", got)
+	}
+
+	// A second delete pushes tombstones to 2/4 = 50%, past the 30%
+	// threshold, and should compact both away synchronously.
+	if err := idx.Delete("b"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if got := idx.TombstoneCount(); got != 0 {
+		t.Fatalf("expected compaction to clear pending tombstones, got TombstoneCount() = %d", got)
+	}
+	if got := idx.Count(); got != 2 {
+		t.Fatalf("expected 2 remaining vectors after compaction, got %d", got)
+	}
+}
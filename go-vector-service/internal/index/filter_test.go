@@ -0,0 +1,160 @@
+// Tests for Filter-scoped search on BruteForceIndex and HNSWIndex.
+package index_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+func filterTestVectors() []types.Vector {
+	return []types.Vector{
+		{ID: "a", Embedding: []float32{1, 0, 0}, Metadata: types.Metadata{DocumentID: "doc-1", PageNumber: 1, Tags: map[string]string{"lang": "en"}}},
+		{ID: "b", Embedding: []float32{0.99, 0.01, 0}, Metadata: types.Metadata{DocumentID: "doc-1", PageNumber: 2, Tags: map[string]string{"lang": "fr"}}},
+		{ID: "c", Embedding: []float32{0.98, 0.02, 0}, Metadata: types.Metadata{DocumentID: "doc-2", PageNumber: 1, Tags: map[string]string{"lang": "en"}}},
+		{ID: "d", Embedding: []float32{0.97, 0.03, 0}, Metadata: types.Metadata{DocumentID: "other-3", PageNumber: 5, Tags: map[string]string{"lang": "en"}}},
+	}
+}
+
+// TestBruteForceSearchFilteredByDocumentID checks equality filtering on
+// Metadata.DocumentID, and that an unfiltered Search still sees every vector.
+func TestBruteForceSearchFilteredByDocumentID(t *testing.T) {
+	idx := index.NewBruteForceIndex(3)
+	for _, v := range filterTestVectors() {
+		if err := idx.Insert(v); err != nil {
+			t.Fatalf("insert %q: %v", v.ID, err)
+		}
+	}
+
+	results := idx.SearchFiltered([]float32{1, 0, 0}, 10, &types.Filter{DocumentID: "doc-1"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results scoped to doc-1, got %d: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if r.Metadata.DocumentID != "doc-1" {
+			t.Errorf("result %q has DocumentID %q, want doc-1", r.ID, r.Metadata.DocumentID)
+		}
+	}
+
+	if all := idx.Search([]float32{1, 0, 0}, 10); len(all) != 4 {
+		t.Fatalf("expected unfiltered Search to return all 4 vectors, got %d", len(all))
+	}
+}
+
+// TestBruteForceSearchFilteredByPrefixRangeAndTags checks the prefix,
+// range, and tag-equality clauses, combined in a single Filter.
+func TestBruteForceSearchFilteredByPrefixRangeAndTags(t *testing.T) {
+	idx := index.NewBruteForceIndex(3)
+	for _, v := range filterTestVectors() {
+		idx.Insert(v)
+	}
+
+	pageMax := 1
+	filter := &types.Filter{
+		DocumentIDPrefix: "doc-1",
+		PageNumberMax:    &pageMax,
+		Tags:             map[string]string{"lang": "en"},
+	}
+	results := idx.SearchFiltered([]float32{1, 0, 0}, 10, filter)
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Fatalf("expected only 'a' to satisfy prefix+range+tag filter, got %+v", results)
+	}
+}
+
+// TestBruteForceSearchFilteredDelete checks that Delete keeps the inverted
+// tag index consistent, so a deleted vector no longer satisfies a filter
+// that used to match it.
+func TestBruteForceSearchFilteredDelete(t *testing.T) {
+	idx := index.NewBruteForceIndex(3)
+	for _, v := range filterTestVectors() {
+		idx.Insert(v)
+	}
+	if err := idx.Delete("a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	results := idx.SearchFiltered([]float32{1, 0, 0}, 10, &types.Filter{DocumentID: "doc-1"})
+	if len(results) != 1 || results[0].ID != "b" {
+		t.Fatalf("expected only 'b' left under doc-1 after deleting 'a', got %+v", results)
+	}
+}
+
+// TestBruteForceSearchConcurrentFiltered checks that SearchConcurrentFiltered
+// scopes results the same way SearchFiltered does, rather than silently
+// searching the whole corpus the way SearchConcurrent does.
+func TestBruteForceSearchConcurrentFiltered(t *testing.T) {
+	idx := index.NewBruteForceIndex(3)
+	for _, v := range filterTestVectors() {
+		idx.Insert(v)
+	}
+
+	results := idx.SearchConcurrentFiltered([]float32{1, 0, 0}, 10, 4, &types.Filter{DocumentID: "doc-1"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results scoped to doc-1, got %d: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if r.Metadata.DocumentID != "doc-1" {
+			t.Errorf("result %q has DocumentID %q, want doc-1", r.ID, r.Metadata.DocumentID)
+		}
+	}
+
+	if all := idx.SearchConcurrent([]float32{1, 0, 0}, 10, 4); len(all) != 4 {
+		t.Fatalf("expected unfiltered SearchConcurrent to still return all 4 vectors, got %d", len(all))
+	}
+}
+
+// TestHNSWSearchFilteredFallsBackToBruteForce checks that a filter narrow
+// enough to be missed by the oversampled graph beam still returns matches
+// via SearchFiltered's brute-force fallback pass.
+func TestHNSWSearchFilteredFallsBackToBruteForce(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	config.EfSearch = 1
+	idx := index.NewHNSWIndex(config)
+
+	for _, v := range filterTestVectors() {
+		if err := idx.Insert(v); err != nil {
+			t.Fatalf("insert %q: %v", v.ID, err)
+		}
+	}
+	// Twenty non-matching vectors, all closer to the query than 'd', fill
+	// the oversampled beam (topK*filterOverFetch = 5), so the graph pass
+	// surfaces no match for the filter and only the fallback can find 'd'.
+	for i := 1; i <= 20; i++ {
+		v := types.Vector{
+			ID:        fmt.Sprintf("filler-%d", i),
+			Embedding: []float32{1, float32(i) * 0.001, 0},
+			Metadata:  types.Metadata{DocumentID: "filler"},
+		}
+		if err := idx.Insert(v); err != nil {
+			t.Fatalf("insert %q: %v", v.ID, err)
+		}
+	}
+
+	results := idx.SearchFiltered([]float32{1, 0, 0}, 1, &types.Filter{DocumentIDPrefix: "other-"})
+	if len(results) != 1 || results[0].ID != "d" {
+		t.Fatalf("expected filtered search to find 'd' via the brute-force fallback, got %+v", results)
+	}
+}
+
+// TestHNSWSearchFilteredMatchesUnfiltered checks that an all-zero Filter
+// behaves identically to a plain Search.
+func TestHNSWSearchFilteredMatchesUnfiltered(t *testing.T) {
+	idx := index.NewHNSWIndex(index.DefaultHNSWConfig(3))
+	for _, v := range filterTestVectors() {
+		idx.Insert(v)
+	}
+
+	query := []float32{1, 0, 0}
+	unfiltered := idx.Search(query, 4)
+	filtered := idx.SearchFiltered(query, 4, &types.Filter{})
+	if len(unfiltered) != len(filtered) {
+		t.Fatalf("expected zero-value Filter to match unfiltered Search, got %d vs %d results", len(filtered), len(unfiltered))
+	}
+	for i := range unfiltered {
+		if unfiltered[i].ID != filtered[i].ID {
+			t.Errorf("rank %d mismatch: unfiltered=%s filtered=%s", i, unfiltered[i].ID, filtered[i].ID)
+		}
+	}
+}
@@ -8,10 +8,16 @@ package index
 
 import (
 	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
 	"math"
 	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 
+	"github.com/documind/vector-service/pkg/simd"
 	"github.com/documind/vector-service/pkg/types"
 )
 
@@ -43,108 +49,379 @@ type HNSWConfig struct {
 
 	// Dimensions is the vector dimensionality.
 	Dimensions int
+
+	// HeuristicSelection enables Algorithm 4 from Malkov & Yashunin (heuristic
+	// neighbor selection) in place of the naive "keep the M closest" selection
+	// used by selectNeighbors and pruneConnections. It generally improves
+	// recall on clustered data at the cost of extra distance computations
+	// during construction.
+	HeuristicSelection bool
+
+	// ExtendCandidates broadens the heuristic's working set to include the
+	// neighbors-of-neighbors of each candidate before pruning. Only used when
+	// HeuristicSelection is true. Improves recall further at higher
+	// construction cost.
+	ExtendCandidates bool
+
+	// KeepPrunedConnections backfills the heuristic's result up to M from the
+	// candidates it discarded, once the main heuristic pass completes. Only
+	// used when HeuristicSelection is true.
+	KeepPrunedConnections bool
+
+	// NormalizeOnInsert stores vectors unit-normalized so that cosine
+	// similarity reduces to a single SIMD dot product (see pkg/simd) instead
+	// of two extra norm computations per comparison. The original L2 norm is
+	// kept on the node for callers that need the raw vector back. Only
+	// takes effect when Metric is Cosine - it's meaningless for metrics
+	// where magnitude carries information, like InnerProduct.
+	NormalizeOnInsert bool
+
+	// Metric is the distance/similarity function used for graph
+	// construction and search. Defaults to Cosine if left zero-valued.
+	Metric Distance
+
+	// TombstoneCompactionThreshold is the fraction of tombstoned
+	// (soft-deleted, not yet compacted) nodes that triggers a background
+	// compaction pass - see Delete and compact. Defaults to 0.2 if left
+	// zero-valued.
+	TombstoneCompactionThreshold float64
 }
 
 // DefaultHNSWConfig returns sensible defaults for HNSW.
 func DefaultHNSWConfig(dimensions int) HNSWConfig {
 	m := 16
 	return HNSWConfig{
-		M:              m,
-		MMax:           m * 2,
-		EfConstruction: 200,
-		EfSearch:       100,
-		ML:             1.0 / math.Log(float64(m)),
-		Dimensions:     dimensions,
+		M:                            m,
+		MMax:                         m * 2,
+		EfConstruction:               200,
+		EfSearch:                     100,
+		ML:                           1.0 / math.Log(float64(m)),
+		Dimensions:                   dimensions,
+		Metric:                       Cosine{},
+		TombstoneCompactionThreshold: defaultTombstoneCompactionThreshold,
 	}
 }
 
-// HNSWNode represents a node in the HNSW graph.
+// defaultTombstoneCompactionThreshold is HNSWConfig.TombstoneCompactionThreshold's
+// zero-value fallback, applied in NewHNSWIndex so callers that build an
+// HNSWConfig by hand instead of through DefaultHNSWConfig still get
+// compaction.
+const defaultTombstoneCompactionThreshold = 0.2
+
+// HNSWNode represents a node in the HNSW graph. Neighbors is guarded by its
+// own mutex rather than the index-wide lock, so concurrent inserts only ever
+// contend on the handful of nodes they actually touch.
 type HNSWNode struct {
-	ID         string
-	Vector     []float32
-	Metadata   types.Metadata
-	Neighbors  [][]string // neighbors[layer] = list of neighbor IDs
-	Layer      int        // Maximum layer this node appears in
+	ID        string
+	Vector    []float32
+	Metadata  types.Metadata
+	Neighbors [][]string // neighbors[layer] = list of neighbor IDs
+	Layer     int        // Maximum layer this node appears in
+	Norm      float32    // Original L2 norm, set when config.NormalizeOnInsert stores Vector pre-normalized
+	mu        sync.Mutex // guards Neighbors
+}
+
+// neighborsSnapshot returns a copy of the neighbor IDs at layer, or nil if
+// the node doesn't reach that layer. Copying under the lock lets callers
+// walk the result without holding the node locked while they recurse into
+// other nodes.
+func (n *HNSWNode) neighborsSnapshot(layer int) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if layer >= len(n.Neighbors) {
+		return nil
+	}
+	out := make([]string, len(n.Neighbors[layer]))
+	copy(out, n.Neighbors[layer])
+	return out
+}
+
+// addLocked appends other's ID to n's neighbor list at layer and, above
+// layer 0, prunes back down to mLayer connections if that overflows it. The
+// caller must already hold n.mu; see linkMutual, the only caller, for why.
+//
+// Layer 0 is exempt from pruning entirely: it's the only layer every node
+// reaches, so it's also the only layer whose connectivity the graph can't
+// recover once lost - an edge pruned away here has no higher layer to fall
+// back through. Tolerating some nodes growing past mLayer at layer 0 is a
+// smaller cost than a node a prune decision cuts off from the rest of the
+// graph. Upper layers exist purely to speed up descent, so pruning them back
+// to mLayer for search-time cost is worth it there.
+func (idx *HNSWIndex) addLocked(n *HNSWNode, layer, mLayer int, otherID string) []string {
+	if layer >= len(n.Neighbors) {
+		return nil
+	}
+	n.Neighbors[layer] = append(n.Neighbors[layer], otherID)
+	if layer == 0 || len(n.Neighbors[layer]) <= mLayer {
+		return nil
+	}
+	before := n.Neighbors[layer]
+	pruned := idx.pruneConnections(n.Vector, n.Neighbors[layer], mLayer, layer)
+	if !contains(pruned, otherID) && len(pruned) > 0 {
+		pruned[len(pruned)-1] = otherID
+	}
+	n.Neighbors[layer] = pruned
+	return droppedIDs(before, pruned)
+}
+
+// droppedIDs returns the IDs present in before but absent from after, used to
+// detect which neighbors a prune pass dropped so their reverse edges can be
+// cleaned up too.
+func droppedIDs(before, after []string) []string {
+	kept := make(map[string]bool, len(after))
+	for _, id := range after {
+		kept[id] = true
+	}
+	var dropped []string
+	for _, id := range before {
+		if !kept[id] {
+			dropped = append(dropped, id)
+		}
+	}
+	return dropped
 }
 
 // HNSWIndex implements the HNSW algorithm for approximate nearest neighbor search.
+//
+// mu guards only the graph's shape: the nodes map itself (so it's safe to
+// grow from multiple goroutines) and the entryPoint/maxLevel pair. It is
+// intentionally not held for the duration of Insert or Search - per-node
+// locks (HNSWNode.mu) protect each node's Neighbors, which is what lets
+// InsertParallel run many inserts concurrently instead of serializing the
+// whole graph on a single coarse lock.
+//
+// linkMu serializes linkMutual, the step that actually commits a new edge
+// (and any pruning it triggers) into the graph. The expensive part of an
+// insert - the ef_construction search each layer runs to find candidate
+// neighbors - still happens fully in parallel across InsertParallel's
+// workers; only the handful of list mutations once candidates are chosen
+// are serialized. That's the part where a multi-node edge commit (the new
+// node, its neighbor, and whichever third node pruning evicts) needs to
+// happen as one atomic step - attempting that with per-node locks alone
+// either leaves a window for one-directional edges when a prune's eviction
+// races another insert's own commit, or deadlocks when three nodes' locks
+// are needed in an order that conflicts with a concurrent commit touching
+// the same nodes in reverse.
 type HNSWIndex struct {
 	nodes      map[string]*HNSWNode
 	entryPoint string
 	maxLevel   int
 	config     HNSWConfig
+	tagIndex   *invertedIndex
 	mu         sync.RWMutex
+	linkMu     sync.Mutex
 	rng        *rand.Rand
+	rngMu      sync.Mutex // guards rng, which is not itself safe for concurrent use
+
+	// tombstones marks nodes Delete has logically removed but whose edges
+	// haven't been torn down yet - see Delete and compact. Guarded by mu,
+	// like the nodes map it shadows.
+	tombstones map[string]bool
+	// compacting is 1 while a compact() goroutine is running, so a burst of
+	// deletes crossing the threshold only ever spawns one at a time.
+	compacting int32
+}
+
+// init registers the built-in Distance implementations with gob, since
+// HNSWConfig.Metric is an interface field and Save/Load round-trip it.
+func init() {
+	gob.Register(Cosine{})
+	gob.Register(L2Squared{})
+	gob.Register(InnerProduct{})
+	gob.Register(Hamming{})
 }
 
 // NewHNSWIndex creates a new HNSW index with the given configuration.
 func NewHNSWIndex(config HNSWConfig) *HNSWIndex {
+	if config.Metric == nil {
+		config.Metric = Cosine{}
+	}
 	return &HNSWIndex{
-		nodes:    make(map[string]*HNSWNode),
-		maxLevel: -1,
-		config:   config,
-		rng:      rand.New(rand.NewSource(42)), // Deterministic for reproducibility
+		nodes:      make(map[string]*HNSWNode),
+		maxLevel:   -1,
+		config:     config,
+		tagIndex:   newInvertedIndex(),
+		rng:        rand.New(rand.NewSource(42)), // Deterministic for reproducibility
+		tombstones: make(map[string]bool),
+	}
+}
+
+// distance returns config.Metric's distance between a and b, taking a fast
+// path via the SIMD dot product when NormalizeOnInsert guarantees both
+// sides are already unit-normalized and the metric is Cosine.
+func (idx *HNSWIndex) distance(a, b []float32) float32 {
+	if idx.config.NormalizeOnInsert {
+		if _, ok := idx.config.Metric.(Cosine); ok {
+			return 1 - simd.Dot(a, b)
+		}
+	}
+	return idx.config.Metric.Distance(a, b)
+}
+
+// similarity returns config.Metric's similarity between a and b, taking the
+// same fast path as distance.
+func (idx *HNSWIndex) similarity(a, b []float32) float32 {
+	if idx.config.NormalizeOnInsert {
+		if _, ok := idx.config.Metric.(Cosine); ok {
+			return simd.Dot(a, b)
+		}
 	}
+	return idx.config.Metric.Similarity(a, b)
 }
 
-// Insert adds a vector to the HNSW index.
+// node looks up a node by ID under the index's read lock, safe to call
+// while other goroutines are growing idx.nodes via Insert.
+func (idx *HNSWIndex) node(id string) *HNSWNode {
+	idx.mu.RLock()
+	n := idx.nodes[id]
+	idx.mu.RUnlock()
+	return n
+}
+
+// isTombstoned reports whether id has been soft-deleted and not yet
+// compacted out of the graph.
+func (idx *HNSWIndex) isTombstoned(id string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.tombstones[id]
+}
+
+// linkMutual connects a and b as neighbors at layer. The whole operation -
+// adding both directions, resolving either side dropping the new edge during
+// its own overflow prune, and cleaning up any third node pruning evicts -
+// runs under idx.linkMu so it commits as one atomic step; see the linkMu
+// field comment for why multiple per-node locks alone aren't enough here.
+// Each node's own mu is still taken around its slice write so readers
+// (Search's neighborsSnapshot) calling concurrently with this never see a
+// torn slice header.
+func (idx *HNSWIndex) linkMutual(layer, mLayer int, a, b *HNSWNode) {
+	idx.linkMu.Lock()
+	defer idx.linkMu.Unlock()
+
+	// linkMu already guarantees only one linkMutual runs at a time, so
+	// holding both a.mu and b.mu together here is safe - there's no second
+	// call anywhere that could be acquiring them in the opposite order.
+	// They're still taken (rather than mutating the slices bare) so readers
+	// like Search's neighborsSnapshot, which only ever hold one node's mu,
+	// never observe a torn slice header mid-write.
+	a.mu.Lock()
+	b.mu.Lock()
+
+	droppedFromA := idx.addLocked(a, layer, mLayer, b.ID)
+	droppedFromB := idx.addLocked(b, layer, mLayer, a.ID)
+
+	b.mu.Unlock()
+	a.mu.Unlock()
+
+	// Clean up third-party nodes pruning dropped from a's or b's list. Each
+	// dropped ID only loses its reverse edge to whichever of a/b actually
+	// dropped it - a dropped ID can independently still hold a perfectly
+	// valid edge to the other one. addLocked never prunes at layer 0 (see
+	// its comment), so droppedFromA/droppedFromB are always empty there and
+	// this only ever runs for layer > 0, where losing an edge doesn't affect
+	// reachability.
+	cleanup := func(droppedIDs []string, from *HNSWNode) {
+		for _, id := range droppedIDs {
+			if id == a.ID || id == b.ID {
+				continue // the new edge itself, handled above
+			}
+			dropped := idx.node(id)
+			if dropped == nil {
+				continue
+			}
+			dropped.mu.Lock()
+			if layer < len(dropped.Neighbors) {
+				dropped.Neighbors[layer] = removeNeighborID(dropped.Neighbors[layer], from.ID)
+			}
+			dropped.mu.Unlock()
+		}
+	}
+	cleanup(droppedFromA, a)
+	cleanup(droppedFromB, b)
+}
+
+// contains reports whether ids includes target.
+func contains(ids []string, target string) bool {
+	for _, id := range ids {
+		if id == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Insert adds a vector to the HNSW index. Safe to call concurrently with
+// other Insert/InsertParallel calls: only the brief map-registration and
+// entry-point update below take the index-wide lock, and all per-node edge
+// mutations go through HNSWNode.mu instead.
 // Time Complexity: O(log n) average case
 func (idx *HNSWIndex) Insert(v types.Vector) error {
-	idx.mu.Lock()
-	defer idx.mu.Unlock()
-
 	// Generate random level for new node
 	level := idx.randomLevel()
 
+	storedVector := v.Embedding
+	var norm float32
+	if idx.config.NormalizeOnInsert {
+		norm = float32(math.Sqrt(float64(DotProduct(v.Embedding, v.Embedding))))
+		storedVector = Normalize(v.Embedding)
+		v.Embedding = storedVector // greedy descent below compares against other stored (normalized) vectors
+	}
+
 	// Create new node
 	node := &HNSWNode{
 		ID:        v.ID,
-		Vector:    v.Embedding,
+		Vector:    storedVector,
 		Metadata:  v.Metadata,
 		Neighbors: make([][]string, level+1),
 		Layer:     level,
+		Norm:      norm,
 	}
 
 	for i := range node.Neighbors {
 		node.Neighbors[i] = make([]string, 0)
 	}
 
+	idx.tagIndex.insert(v.ID, v.Metadata)
+
+	idx.mu.Lock()
 	idx.nodes[v.ID] = node
+	delete(idx.tombstones, v.ID) // reinserting the same ID revives any pending tombstone
 
 	// If this is the first node, set as entry point
 	if idx.entryPoint == "" {
 		idx.entryPoint = v.ID
 		idx.maxLevel = level
+		idx.mu.Unlock()
 		return nil
 	}
+	entryID := idx.entryPoint
+	maxLevel := idx.maxLevel
+	idx.mu.Unlock()
 
 	// Find entry point for insertion
-	currentNode := idx.nodes[idx.entryPoint]
-	entryID := idx.entryPoint
+	currentNode := idx.node(entryID)
 
 	// Traverse from top level down to level+1 (greedy search)
-	for l := idx.maxLevel; l > level; l-- {
+	for l := maxLevel; l > level; l-- {
 		changed := true
 		for changed {
 			changed = false
-			if l < len(currentNode.Neighbors) {
-				for _, neighborID := range currentNode.Neighbors[l] {
-					neighbor := idx.nodes[neighborID]
-					if neighbor == nil {
-						continue
-					}
-					if CosineDistance(v.Embedding, neighbor.Vector) < CosineDistance(v.Embedding, currentNode.Vector) {
-						currentNode = neighbor
-						entryID = neighborID
-						changed = true
-					}
+			for _, neighborID := range currentNode.neighborsSnapshot(l) {
+				neighbor := idx.node(neighborID)
+				if neighbor == nil {
+					continue
+				}
+				if idx.distance(v.Embedding, neighbor.Vector) < idx.distance(v.Embedding, currentNode.Vector) {
+					currentNode = neighbor
+					entryID = neighborID
+					changed = true
 				}
 			}
 		}
 	}
 
 	// For each layer from min(level, maxLevel) down to 0
-	for l := min(level, idx.maxLevel); l >= 0; l-- {
+	for l := min(level, maxLevel); l >= 0; l-- {
 		// Find ef_construction nearest neighbors at this layer
 		neighbors := idx.searchLayer(v.Embedding, entryID, idx.config.EfConstruction, l)
 
@@ -154,23 +431,19 @@ func (idx *HNSWIndex) Insert(v types.Vector) error {
 			mLayer = idx.config.MMax
 		}
 
-		selectedNeighbors := idx.selectNeighbors(v.Embedding, neighbors, mLayer)
+		selectedNeighbors := idx.selectNeighbors(v.Embedding, neighbors, mLayer, l)
 
-		// Add bidirectional connections
-		node.Neighbors[l] = make([]string, 0, len(selectedNeighbors))
+		// node is registered in idx.nodes already (above), so as soon as one
+		// of its layers gets a first edge, another concurrent insert may
+		// reach it via searchLayer and link to it too - linkMutual locks
+		// both sides of each edge together so that race can't leave one
+		// direction clobbered or pruned without its reverse cleaned up.
 		for _, n := range selectedNeighbors {
-			node.Neighbors[l] = append(node.Neighbors[l], n.ID)
-
-			// Add reverse connection
-			neighbor := idx.nodes[n.ID]
-			if neighbor != nil && l < len(neighbor.Neighbors) {
-				neighbor.Neighbors[l] = append(neighbor.Neighbors[l], v.ID)
-
-				// Prune if exceeding maximum connections
-				if len(neighbor.Neighbors[l]) > mLayer {
-					neighbor.Neighbors[l] = idx.pruneConnections(neighbor.Vector, neighbor.Neighbors[l], mLayer)
-				}
+			neighbor := idx.node(n.ID)
+			if neighbor == nil {
+				continue
 			}
+			idx.linkMutual(l, mLayer, node, neighbor)
 		}
 
 		// Update entry for next layer
@@ -180,15 +453,18 @@ func (idx *HNSWIndex) Insert(v types.Vector) error {
 	}
 
 	// Update entry point if new node has higher level
+	idx.mu.Lock()
 	if level > idx.maxLevel {
 		idx.entryPoint = v.ID
 		idx.maxLevel = level
 	}
+	idx.mu.Unlock()
 
 	return nil
 }
 
-// InsertBatch adds multiple vectors efficiently.
+// InsertBatch adds multiple vectors efficiently, one at a time in order.
+// Use InsertParallel for concurrent bulk loads.
 func (idx *HNSWIndex) InsertBatch(vectors []types.Vector) (int, error) {
 	for _, v := range vectors {
 		if err := idx.Insert(v); err != nil {
@@ -198,66 +474,224 @@ func (idx *HNSWIndex) InsertBatch(vectors []types.Vector) (int, error) {
 	return len(vectors), nil
 }
 
-// Search finds the top-k approximate nearest neighbors.
+// InsertParallel shards vectors across workers goroutines and inserts them
+// concurrently, relying on Insert's per-node locking to make that safe. It
+// returns the number of vectors successfully inserted and the first error
+// encountered, if any; a failure on one vector does not stop the others.
+func (idx *HNSWIndex) InsertParallel(vectors []types.Vector, workers int) (int, error) {
+	if workers <= 0 {
+		workers = 4
+	}
+	if len(vectors) == 0 {
+		return 0, nil
+	}
+	if workers > len(vectors) {
+		workers = len(vectors)
+	}
+
+	jobs := make(chan types.Vector)
+	var wg sync.WaitGroup
+	var inserted int64
+	var errMu sync.Mutex
+	var firstErr error
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range jobs {
+				if err := idx.Insert(v); err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+					continue
+				}
+				atomic.AddInt64(&inserted, 1)
+			}
+		}()
+	}
+
+	for _, v := range vectors {
+		jobs <- v
+	}
+	close(jobs)
+	wg.Wait()
+
+	return int(inserted), firstErr
+}
+
+// Search finds the top-k approximate nearest neighbors. Safe to call
+// concurrently with Insert/InsertParallel: it only takes the index-wide lock
+// briefly to snapshot the entry point, then walks the graph through
+// per-node locks (see HNSWNode.mu).
 // Time Complexity: O(log n) average case
 func (idx *HNSWIndex) Search(query []float32, topK int) []types.SearchResult {
+	return idx.SearchFiltered(query, topK, nil)
+}
+
+// filterOverFetch is how many multiples of topK the search beam (ef_search)
+// is widened to when a Filter is present, since post-filtering below
+// discards any candidate that fails the predicate before the final top-k
+// cut - without it, a selective filter would starve the result set.
+const filterOverFetch = 5
+
+// SearchFiltered is Search scoped to vectors whose Metadata satisfies
+// filter. A nil or zero-value filter behaves exactly like Search. The graph
+// beam (ef_search) is oversampled to max(EfSearch, topK*filterOverFetch)
+// before post-filtering its candidates; if that still leaves fewer than
+// topK matches, it falls back to a filtered brute-force pass over every
+// node so a narrow filter doesn't silently under-return.
+func (idx *HNSWIndex) SearchFiltered(query []float32, topK int, filter *types.Filter) []types.SearchResult {
 	idx.mu.RLock()
-	defer idx.mu.RUnlock()
+	entryID := idx.entryPoint
+	maxLevel := idx.maxLevel
+	empty := len(idx.nodes) == 0
+	idx.mu.RUnlock()
 
-	if len(idx.nodes) == 0 || idx.entryPoint == "" {
+	if empty || entryID == "" {
 		return []types.SearchResult{}
 	}
 
+	if idx.config.NormalizeOnInsert {
+		query = Normalize(query)
+	}
+
 	// Start from entry point
-	currentNode := idx.nodes[idx.entryPoint]
-	entryID := idx.entryPoint
+	currentNode := idx.node(entryID)
 
 	// Traverse from top level down to level 1 (greedy search)
-	for l := idx.maxLevel; l > 0; l-- {
+	for l := maxLevel; l > 0; l-- {
 		changed := true
 		for changed {
 			changed = false
-			if l < len(currentNode.Neighbors) {
-				for _, neighborID := range currentNode.Neighbors[l] {
-					neighbor := idx.nodes[neighborID]
-					if neighbor == nil {
-						continue
-					}
-					if CosineDistance(query, neighbor.Vector) < CosineDistance(query, currentNode.Vector) {
-						currentNode = neighbor
-						entryID = neighborID
-						changed = true
-					}
+			for _, neighborID := range currentNode.neighborsSnapshot(l) {
+				neighbor := idx.node(neighborID)
+				if neighbor == nil {
+					continue
+				}
+				if idx.distance(query, neighbor.Vector) < idx.distance(query, currentNode.Vector) {
+					currentNode = neighbor
+					entryID = neighborID
+					changed = true
 				}
 			}
 		}
 	}
 
+	efSearch := idx.config.EfSearch
+	if !filter.IsZero() {
+		if oversampled := topK * filterOverFetch; oversampled > efSearch {
+			efSearch = oversampled
+		}
+	}
+	if efSearch < topK {
+		efSearch = topK
+	}
+
 	// Search at layer 0 with ef_search width
-	candidates := idx.searchLayer(query, entryID, idx.config.EfSearch, 0)
+	candidates := idx.searchLayer(query, entryID, efSearch, 0)
 
 	// Return top-k results
 	results := make([]types.SearchResult, 0, topK)
-	for i := 0; i < len(candidates) && i < topK; i++ {
-		node := idx.nodes[candidates[i].ID]
-		if node != nil {
-			results = append(results, types.SearchResult{
-				ID:       candidates[i].ID,
-				Score:    CosineSimilarity(query, node.Vector), // Convert distance to similarity
-				Metadata: node.Metadata,
-			})
+	for i := 0; i < len(candidates) && len(results) < topK; i++ {
+		node := idx.node(candidates[i].ID)
+		if node == nil || idx.isTombstoned(candidates[i].ID) || !filter.Matches(node.Metadata) {
+			continue
 		}
+		results = append(results, types.SearchResult{
+			ID:       candidates[i].ID,
+			Score:    idx.similarity(query, node.Vector), // Convert distance to similarity
+			Metadata: node.Metadata,
+		})
+	}
+
+	if filter.IsZero() || len(results) >= topK {
+		return results
+	}
+
+	return idx.bruteForceFiltered(query, topK, filter)
+}
+
+// SearchIter is Search, but returns a ResultIterator backed by a pooled
+// scratch slice instead of a freshly allocated one - see SearchIterFiltered.
+func (idx *HNSWIndex) SearchIter(query []float32, topK int) ResultIterator {
+	return idx.SearchIterFiltered(query, topK, nil)
+}
+
+// SearchIterFiltered is SearchFiltered, copied into a pooled scratch slice
+// drawn from resultSlicePool instead of returned as a freshly allocated one,
+// for callers (e.g. HandleSearch's NDJSON path) that want the same streaming
+// ResultIterator interface BruteForceIndex.SearchIterFiltered returns.
+// SearchFiltered's own internal allocations (its candidate beam and, on the
+// filtered fallback path, its own resultHeap) aren't pooled here - the graph
+// traversal above isn't under BruteForceIndex's simple score-every-vector
+// loop, so sharing its heap pool isn't a direct fit the way it is for
+// BruteForceIndex.
+func (idx *HNSWIndex) SearchIterFiltered(query []float32, topK int, filter *types.Filter) ResultIterator {
+	found := idx.SearchFiltered(query, topK, filter)
+
+	results := resultSlicePool.Get().([]types.SearchResult)
+	if cap(results) < len(found) {
+		results = make([]types.SearchResult, len(found))
+	} else {
+		results = results[:len(found)]
 	}
+	copy(results, found)
 
+	return &sliceIterator{results: results}
+}
+
+// bruteForceFiltered linearly scans every node for filter matches, scoped by
+// the inverted tag index when filter has an equality clause it can answer.
+// Used as SearchFiltered's fallback when post-filtering the ANN candidate
+// set doesn't surface enough results.
+func (idx *HNSWIndex) bruteForceFiltered(query []float32, topK int, filter *types.Filter) []types.SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	candidateIDs, scoped := idx.tagIndex.candidateIDs(filter)
+
+	h := &resultHeap{}
+	heap.Init(h)
+
+	for id, node := range idx.nodes {
+		if idx.tombstones[id] {
+			continue
+		}
+		if scoped && !candidateIDs[id] {
+			continue
+		}
+		if !filter.Matches(node.Metadata) {
+			continue
+		}
+		score := idx.similarity(query, node.Vector)
+		if h.Len() < topK {
+			heap.Push(h, types.SearchResult{ID: id, Score: score, Metadata: node.Metadata})
+		} else if score > (*h)[0].Score {
+			heap.Pop(h)
+			heap.Push(h, types.SearchResult{ID: id, Score: score, Metadata: node.Metadata})
+		}
+	}
+
+	results := make([]types.SearchResult, h.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(h).(types.SearchResult)
+	}
 	return results
 }
 
-// searchLayer performs beam search at a specific layer.
+// searchLayer performs beam search at a specific layer. It takes each
+// visited node's mutex only long enough to copy that node's neighbor
+// slice (via neighborsSnapshot), then releases it before recursing into
+// those neighbors - so a concurrent insert touching an unrelated part of the
+// graph never blocks this traversal.
 func (idx *HNSWIndex) searchLayer(query []float32, entryID string, ef int, layer int) []distanceNode {
 	visited := make(map[string]bool)
 	visited[entryID] = true
 
-	entryNode := idx.nodes[entryID]
+	entryNode := idx.node(entryID)
 	if entryNode == nil {
 		return nil
 	}
@@ -270,7 +704,7 @@ func (idx *HNSWIndex) searchLayer(query []float32, entryID string, ef int, layer
 	results := &distanceHeap{}
 	heap.Init(results)
 
-	entryDist := CosineDistance(query, entryNode.Vector)
+	entryDist := idx.distance(query, entryNode.Vector)
 	heap.Push(candidates, distanceNode{ID: entryID, Distance: entryDist, isMaxHeap: false})
 	heap.Push(results, distanceNode{ID: entryID, Distance: entryDist, isMaxHeap: true})
 
@@ -287,23 +721,23 @@ func (idx *HNSWIndex) searchLayer(query []float32, entryID string, ef int, layer
 		}
 
 		// Explore neighbors
-		node := idx.nodes[closest.ID]
-		if node == nil || layer >= len(node.Neighbors) {
+		node := idx.node(closest.ID)
+		if node == nil {
 			continue
 		}
 
-		for _, neighborID := range node.Neighbors[layer] {
+		for _, neighborID := range node.neighborsSnapshot(layer) {
 			if visited[neighborID] {
 				continue
 			}
 			visited[neighborID] = true
 
-			neighbor := idx.nodes[neighborID]
+			neighbor := idx.node(neighborID)
 			if neighbor == nil {
 				continue
 			}
 
-			dist := CosineDistance(query, neighbor.Vector)
+			dist := idx.distance(query, neighbor.Vector)
 
 			if results.Len() < ef {
 				heap.Push(candidates, distanceNode{ID: neighborID, Distance: dist, isMaxHeap: false})
@@ -331,58 +765,135 @@ func (idx *HNSWIndex) searchLayer(query []float32, entryID string, ef int, layer
 }
 
 // selectNeighbors selects the best M neighbors from candidates.
-// Uses simple selection (can be enhanced with heuristic selection).
-func (idx *HNSWIndex) selectNeighbors(query []float32, candidates []distanceNode, m int) []distanceNode {
+// Falls back to simple "closest M" truncation unless the index is configured
+// for heuristic selection (config.HeuristicSelection).
+func (idx *HNSWIndex) selectNeighbors(query []float32, candidates []distanceNode, m int, layer int) []distanceNode {
+	if idx.config.HeuristicSelection {
+		return idx.selectNeighborsHeuristic(query, candidates, m, layer)
+	}
 	if len(candidates) <= m {
 		return candidates
 	}
 	return candidates[:m]
 }
 
-// pruneConnections removes connections to maintain M limit.
-func (idx *HNSWIndex) pruneConnections(nodeVector []float32, neighbors []string, m int) []string {
-	if len(neighbors) <= m {
-		return neighbors
+// selectNeighborsHeuristic implements Algorithm 4 from Malkov & Yashunin:
+// it walks the candidate set in ascending distance from q and only accepts a
+// candidate e into the result if e is closer to q than to every already
+// accepted neighbor r. This spreads connections across clusters instead of
+// greedily keeping the M closest points, which tends to bunch up within a
+// single cluster.
+func (idx *HNSWIndex) selectNeighborsHeuristic(query []float32, candidates []distanceNode, m int, layer int) []distanceNode {
+	working := make([]distanceNode, len(candidates))
+	copy(working, candidates)
+
+	if idx.config.ExtendCandidates {
+		seen := make(map[string]bool, len(candidates))
+		for _, c := range candidates {
+			seen[c.ID] = true
+		}
+		for _, c := range candidates {
+			node := idx.node(c.ID)
+			if node == nil {
+				continue
+			}
+			for _, nid := range node.neighborsSnapshot(layer) {
+				if seen[nid] {
+					continue
+				}
+				seen[nid] = true
+				neighbor := idx.node(nid)
+				if neighbor == nil {
+					continue
+				}
+				working = append(working, distanceNode{ID: nid, Distance: idx.distance(query, neighbor.Vector)})
+			}
+		}
 	}
 
-	// Calculate distances and sort
-	type neighborDist struct {
-		id   string
-		dist float32
+	sort.Slice(working, func(i, j int) bool { return working[i].Distance < working[j].Distance })
+
+	result := make([]distanceNode, 0, m)
+	discarded := make([]distanceNode, 0, len(working))
+
+	for _, e := range working {
+		if len(result) >= m {
+			break
+		}
+
+		eNode := idx.node(e.ID)
+		acceptIntoResult := true
+		for _, r := range result {
+			rNode := idx.node(r.ID)
+			if eNode == nil || rNode == nil {
+				continue
+			}
+			if idx.distance(eNode.Vector, rNode.Vector) <= e.Distance {
+				acceptIntoResult = false
+				break
+			}
+		}
+
+		if acceptIntoResult {
+			result = append(result, e)
+		} else {
+			discarded = append(discarded, e)
+		}
+	}
+
+	if idx.config.KeepPrunedConnections {
+		for _, d := range discarded {
+			if len(result) >= m {
+				break
+			}
+			result = append(result, d)
+		}
 	}
 
-	dists := make([]neighborDist, 0, len(neighbors))
+	return result
+}
+
+// pruneConnections removes connections to maintain the M limit for a layer.
+// Uses the heuristic selection when config.HeuristicSelection is set,
+// otherwise keeps the M closest neighbors by distance.
+func (idx *HNSWIndex) pruneConnections(nodeVector []float32, neighbors []string, m int, layer int) []string {
+	if len(neighbors) <= m {
+		return neighbors
+	}
+
+	candidates := make([]distanceNode, 0, len(neighbors))
 	for _, nid := range neighbors {
-		node := idx.nodes[nid]
+		node := idx.node(nid)
 		if node != nil {
-			dists = append(dists, neighborDist{
-				id:   nid,
-				dist: CosineDistance(nodeVector, node.Vector),
-			})
+			candidates = append(candidates, distanceNode{ID: nid, Distance: idx.distance(nodeVector, node.Vector)})
 		}
 	}
 
-	// Sort by distance
-	for i := 0; i < len(dists)-1; i++ {
-		for j := i + 1; j < len(dists); j++ {
-			if dists[j].dist < dists[i].dist {
-				dists[i], dists[j] = dists[j], dists[i]
-			}
-		}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Distance < candidates[j].Distance })
+
+	selected := candidates
+	if idx.config.HeuristicSelection {
+		selected = idx.selectNeighborsHeuristic(nodeVector, candidates, m, layer)
+	} else if len(selected) > m {
+		selected = selected[:m]
 	}
 
-	// Keep closest m
-	result := make([]string, 0, m)
-	for i := 0; i < m && i < len(dists); i++ {
-		result = append(result, dists[i].id)
+	result := make([]string, 0, len(selected))
+	for _, s := range selected {
+		result = append(result, s.ID)
 	}
 
 	return result
 }
 
-// randomLevel generates a random level for a new node.
+// randomLevel generates a random level for a new node. Guarded by rngMu
+// since math/rand.Rand is not itself safe for concurrent use and Insert may
+// run from multiple goroutines via InsertParallel.
 // Level distribution follows: P(level = l) = (1/M)^l * (1 - 1/M)
 func (idx *HNSWIndex) randomLevel() int {
+	idx.rngMu.Lock()
+	defer idx.rngMu.Unlock()
+
 	level := 0
 	for idx.rng.Float64() < idx.config.ML && level < 16 {
 		level++
@@ -390,11 +901,195 @@ func (idx *HNSWIndex) randomLevel() int {
 	return level
 }
 
-// Count returns the number of vectors in the index.
+// Delete soft-deletes a node: it's tombstoned so Search/Exists/Count treat
+// it as gone immediately, but its edges are left in place so the graph
+// traversal that serves other nodes' searches can still walk through it to
+// reach whatever is on the other side - tearing down edges synchronously on
+// every delete would otherwise make a single delete as expensive as an
+// insert. The actual graph surgery (removeNode) runs later, amortized across
+// every pending tombstone, once they cross TombstoneCompactionThreshold; see
+// compact.
+func (idx *HNSWIndex) Delete(id string) error {
+	idx.mu.Lock()
+	node, ok := idx.nodes[id]
+	if !ok || idx.tombstones[id] {
+		idx.mu.Unlock()
+		return fmt.Errorf("hnsw: vector %q not found", id)
+	}
+	idx.tombstones[id] = true
+	idx.tagIndex.remove(id, node.Metadata)
+	ratio := float64(len(idx.tombstones)) / float64(len(idx.nodes))
+	idx.mu.Unlock()
+
+	threshold := idx.config.TombstoneCompactionThreshold
+	if threshold <= 0 {
+		threshold = defaultTombstoneCompactionThreshold
+	}
+	if ratio >= threshold && atomic.CompareAndSwapInt32(&idx.compacting, 0, 1) {
+		go idx.compact()
+	}
+
+	return nil
+}
+
+// compact physically removes every currently-tombstoned node via removeNode,
+// amortizing the edge-repair cost Delete defers across however many deletes
+// accumulated since the last pass. Runs as its own goroutine, kicked off by
+// whichever Delete call first brings the tombstone ratio to or past the
+// compaction threshold; idx.compacting keeps concurrent Deletes from
+// spawning more than one at a time.
+func (idx *HNSWIndex) compact() {
+	defer atomic.StoreInt32(&idx.compacting, 0)
+
+	idx.mu.RLock()
+	pending := make([]string, 0, len(idx.tombstones))
+	for id := range idx.tombstones {
+		pending = append(pending, id)
+	}
+	idx.mu.RUnlock()
+
+	for _, id := range pending {
+		idx.mu.Lock()
+		if !idx.tombstones[id] {
+			idx.mu.Unlock()
+			continue // revived by an Insert/Upsert of the same ID since pending was built
+		}
+		delete(idx.tombstones, id)
+		idx.mu.Unlock()
+
+		idx.removeNode(id)
+	}
+}
+
+// removeNode physically removes a node from the graph, patching up
+// bidirectional edges at every layer it appeared in. If the removed node
+// was the entry point, a new entry point is chosen from the remaining node
+// with the highest layer. Called directly by Upsert (which needs the old
+// node's edges gone before reinserting the same ID right away) and by
+// compact (for tombstones Delete deferred).
+func (idx *HNSWIndex) removeNode(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	node, ok := idx.nodes[id]
+	if !ok {
+		return fmt.Errorf("hnsw: vector %q not found", id)
+	}
+	delete(idx.tombstones, id)
+
+	node.mu.Lock()
+	neighborsByLayer := make([][]string, len(node.Neighbors))
+	for l, neighbors := range node.Neighbors {
+		neighborsByLayer[l] = append([]string(nil), neighbors...)
+	}
+	node.mu.Unlock()
+
+	for layer, neighbors := range neighborsByLayer {
+		for _, nid := range neighbors {
+			neighbor := idx.nodes[nid]
+			if neighbor == nil {
+				continue
+			}
+			neighbor.mu.Lock()
+			if layer < len(neighbor.Neighbors) {
+				neighbor.Neighbors[layer] = removeNeighborID(neighbor.Neighbors[layer], id)
+			}
+			neighbor.mu.Unlock()
+		}
+	}
+
+	delete(idx.nodes, id)
+
+	if idx.entryPoint == id {
+		idx.entryPoint = ""
+		idx.maxLevel = -1
+		for nid, n := range idx.nodes {
+			if n.Layer > idx.maxLevel {
+				idx.maxLevel = n.Layer
+				idx.entryPoint = nid
+			}
+		}
+	}
+
+	return nil
+}
+
+// Exists reports whether a vector with the given ID is currently live in
+// the index (present and not tombstoned).
+func (idx *HNSWIndex) Exists(id string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	_, ok := idx.nodes[id]
+	return ok && !idx.tombstones[id]
+}
+
+// TombstoneCount returns the number of nodes that have been deleted but not
+// yet physically compacted out of the graph.
+func (idx *HNSWIndex) TombstoneCount() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.tombstones)
+}
+
+// DeleteBatch removes each of ids from the index, stopping at the first one
+// not found. Returns how many were removed before that.
+func (idx *HNSWIndex) DeleteBatch(ids []string) (int, error) {
+	for i, id := range ids {
+		if err := idx.Delete(id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// Upsert replaces the vector with v.ID if it already exists, or inserts v as
+// new otherwise. An existing node is fully removed (via removeNode, not
+// Delete's tombstoning - the same ID is about to be reinserted immediately,
+// so there's no point deferring the edge cleanup) and reinserted rather
+// than mutated in place, since changing its vector would require
+// re-evaluating every edge Insert's candidate search chose for the old one.
+func (idx *HNSWIndex) Upsert(v types.Vector) error {
+	if idx.Exists(v.ID) {
+		if err := idx.removeNode(v.ID); err != nil {
+			return err
+		}
+	}
+	return idx.Insert(v)
+}
+
+// removeNeighborID returns neighbors with the first occurrence of target removed.
+func removeNeighborID(neighbors []string, target string) []string {
+	for i, id := range neighbors {
+		if id == target {
+			return append(neighbors[:i], neighbors[i+1:]...)
+		}
+	}
+	return neighbors
+}
+
+// EntryPoint returns the ID of the current graph entry point, and false if
+// the index is empty.
+func (idx *HNSWIndex) EntryPoint() (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.entryPoint, idx.entryPoint != ""
+}
+
+// Neighbors returns a copy of node id's neighbor IDs at layer, or nil if the
+// node doesn't exist or doesn't reach that layer.
+func (idx *HNSWIndex) Neighbors(id string, layer int) []string {
+	node := idx.node(id)
+	if node == nil {
+		return nil
+	}
+	return node.neighborsSnapshot(layer)
+}
+
+// Count returns the number of live (non-tombstoned) vectors in the index.
 func (idx *HNSWIndex) Count() int {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
-	return len(idx.nodes)
+	return len(idx.nodes) - len(idx.tombstones)
 }
 
 // Dimensions returns the dimensionality of vectors in the index.
@@ -402,6 +1097,126 @@ func (idx *HNSWIndex) Dimensions() int {
 	return idx.config.Dimensions
 }
 
+// hnswSnapshotVersion is bumped whenever the on-disk layout of hnswSnapshot
+// changes, so Load can refuse snapshots it doesn't know how to read.
+const hnswSnapshotVersion = 1
+
+// hnswSnapshot is the gob-serializable representation of an HNSWIndex.
+type hnswSnapshot struct {
+	Version    int
+	Config     HNSWConfig
+	EntryPoint string
+	MaxLevel   int
+	Nodes      []hnswSnapshotNode
+}
+
+// hnswSnapshotNode is the gob-serializable representation of an HNSWNode.
+type hnswSnapshotNode struct {
+	ID        string
+	Vector    []float32
+	Metadata  types.Metadata
+	Neighbors [][]string
+	Layer     int
+}
+
+// Save serializes the graph's live (non-tombstoned) nodes (per-layer
+// neighbor lists, entry point, and config) to w using gob, behind a
+// versioned header so Load can reject snapshots written by an incompatible
+// version. A tombstoned node not yet compacted out is skipped, the same as
+// Count/Exists already treat it as gone.
+func (idx *HNSWIndex) Save(w io.Writer) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	snap := hnswSnapshot{
+		Version:    hnswSnapshotVersion,
+		Config:     idx.config,
+		EntryPoint: idx.entryPoint,
+		MaxLevel:   idx.maxLevel,
+		Nodes:      make([]hnswSnapshotNode, 0, len(idx.nodes)-len(idx.tombstones)),
+	}
+	if idx.tombstones[snap.EntryPoint] {
+		// The live entry point is about to be skipped below - re-elect from
+		// the remaining live nodes the same way removeNode does, so the
+		// loaded graph isn't left entry-point-less despite having survivors.
+		snap.EntryPoint = ""
+		snap.MaxLevel = -1
+		for nid, n := range idx.nodes {
+			if idx.tombstones[nid] {
+				continue
+			}
+			if n.Layer > snap.MaxLevel {
+				snap.MaxLevel = n.Layer
+				snap.EntryPoint = nid
+			}
+		}
+	}
+	for _, n := range idx.nodes {
+		if idx.tombstones[n.ID] {
+			continue
+		}
+		n.mu.Lock()
+		neighbors := make([][]string, len(n.Neighbors))
+		for l, ids := range n.Neighbors {
+			neighbors[l] = append([]string(nil), ids...)
+		}
+		n.mu.Unlock()
+
+		snap.Nodes = append(snap.Nodes, hnswSnapshotNode{
+			ID:        n.ID,
+			Vector:    n.Vector,
+			Metadata:  n.Metadata,
+			Neighbors: neighbors,
+			Layer:     n.Layer,
+		})
+	}
+
+	if err := gob.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("hnsw: encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the graph's contents with a snapshot previously written by
+// Save. Callers typically do this once at startup before serving traffic.
+func (idx *HNSWIndex) Load(r io.Reader) error {
+	var snap hnswSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("hnsw: decode snapshot: %w", err)
+	}
+	if snap.Version != hnswSnapshotVersion {
+		return fmt.Errorf("hnsw: unsupported snapshot version %d (want %d)", snap.Version, hnswSnapshotVersion)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.config = snap.Config
+	if idx.config.Metric == nil {
+		idx.config.Metric = Cosine{} // snapshot predates the Metric field
+	}
+	idx.entryPoint = snap.EntryPoint
+	idx.maxLevel = snap.MaxLevel
+	idx.nodes = make(map[string]*HNSWNode, len(snap.Nodes))
+	idx.tombstones = make(map[string]bool)
+	idx.tagIndex = newInvertedIndex()
+	for _, n := range snap.Nodes {
+		idx.nodes[n.ID] = &HNSWNode{
+			ID:        n.ID,
+			Vector:    n.Vector,
+			Metadata:  n.Metadata,
+			Neighbors: n.Neighbors,
+			Layer:     n.Layer,
+		}
+		idx.tagIndex.insert(n.ID, n.Metadata)
+	}
+	if idx.rng == nil {
+		idx.rng = rand.New(rand.NewSource(42))
+	}
+
+	return nil
+}
+
 // distanceNode pairs an ID with its distance for heap operations.
 type distanceNode struct {
 	ID        string
@@ -0,0 +1,88 @@
+// Concurrency tests for HNSWIndex. Run with -race to exercise the per-node
+// locking added for InsertParallel.
+package index_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestHNSWInsertParallelBidirectionalAndReachable inserts a batch of random
+// vectors from 8 goroutines via InsertParallel and checks the resulting
+// graph is internally consistent: every edge has a matching reverse edge,
+// and every node is reachable from the entry point by following layer-0
+// edges.
+func TestHNSWInsertParallelBidirectionalAndReachable(t *testing.T) {
+	const n = 10000
+	const dim = 16
+	const workers = 8
+
+	config := index.DefaultHNSWConfig(dim)
+	idx := index.NewHNSWIndex(config)
+
+	vectors := make([]types.Vector, n)
+	for i := range vectors {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rand.Float32()*2 - 1
+		}
+		vectors[i] = types.Vector{ID: fmt.Sprintf("vec_%d", i), Embedding: v}
+	}
+
+	inserted, err := idx.InsertParallel(vectors, workers)
+	if err != nil {
+		t.Fatalf("InsertParallel returned error: %v", err)
+	}
+	if inserted != n {
+		t.Fatalf("expected %d vectors inserted, got %d", n, inserted)
+	}
+	if got := idx.Count(); got != n {
+		t.Fatalf("expected %d vectors in index, got %d", n, got)
+	}
+
+	graph := make(map[string][]string, n)
+	for _, v := range vectors {
+		graph[v.ID] = idx.Neighbors(v.ID, 0)
+	}
+
+	for id, neighbors := range graph {
+		for _, nid := range neighbors {
+			found := false
+			for _, b := range graph[nid] {
+				if b == id {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("edge %s -> %s has no reverse edge", id, nid)
+			}
+		}
+	}
+
+	entryID, ok := idx.EntryPoint()
+	if !ok {
+		t.Fatalf("expected an entry point after inserting %d vectors", n)
+	}
+
+	visited := map[string]bool{entryID: true}
+	queue := []string{entryID}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, nid := range graph[cur] {
+			if !visited[nid] {
+				visited[nid] = true
+				queue = append(queue, nid)
+			}
+		}
+	}
+
+	if len(visited) != n {
+		t.Fatalf("expected all %d nodes reachable from entry point, reached %d", n, len(visited))
+	}
+}
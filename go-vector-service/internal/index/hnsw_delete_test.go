@@ -0,0 +1,147 @@
+// Tests for HNSW heuristic neighbor selection, Delete, and snapshot round-tripping.
+package index_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestHNSWHeuristicSelectionCorrectness ensures heuristic selection still
+// returns the nearest vector among the results.
+func TestHNSWHeuristicSelectionCorrectness(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	config.HeuristicSelection = true
+	config.ExtendCandidates = true
+	config.KeepPrunedConnections = true
+	idx := index.NewHNSWIndex(config)
+
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	idx.Insert(types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+	idx.Insert(types.Vector{ID: "c", Embedding: []float32{0.9, 0.1, 0}})
+
+	results := idx.Search([]float32{1, 0, 0}, 3)
+	if !containsID(results, "a") {
+		t.Errorf("expected 'a' among results, got %+v", results)
+	}
+}
+
+// containsID reports whether id appears among results.
+func containsID(results []types.SearchResult, id string) bool {
+	for _, r := range results {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TestHNSWDelete verifies a deleted node is unreachable and no longer
+// referenced by any remaining neighbor list.
+func TestHNSWDelete(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewHNSWIndex(config)
+
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	idx.Insert(types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+	idx.Insert(types.Vector{ID: "c", Embedding: []float32{0.9, 0.1, 0}})
+
+	if err := idx.Delete("a"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if idx.Count() != 2 {
+		t.Fatalf("expected 2 remaining vectors, got %d", idx.Count())
+	}
+
+	results := idx.Search([]float32{1, 0, 0}, 3)
+	for _, r := range results {
+		if r.ID == "a" {
+			t.Errorf("deleted vector 'a' still reachable from search")
+		}
+	}
+
+	if err := idx.Delete("does-not-exist"); err == nil {
+		t.Errorf("expected error deleting unknown id")
+	}
+}
+
+// TestHNSWDeleteTombstonesUntilCompaction verifies a deleted node is
+// reported as gone (Exists, Count, Search) immediately, but that
+// TombstoneCount only drops back to zero once compaction - triggered
+// automatically once the tombstone ratio crosses
+// TombstoneCompactionThreshold - has had a chance to run.
+func TestHNSWDeleteTombstonesUntilCompaction(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	config.TombstoneCompactionThreshold = 0.5
+	idx := index.NewHNSWIndex(config)
+
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	idx.Insert(types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+
+	if err := idx.Delete("a"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if idx.Exists("a") {
+		t.Error("expected 'a' to be gone immediately after Delete")
+	}
+	if got := idx.Count(); got != 1 {
+		t.Fatalf("expected Count to exclude the tombstoned vector immediately, got %d", got)
+	}
+	for _, r := range idx.Search([]float32{1, 0, 0}, 2) {
+		if r.ID == "a" {
+			t.Errorf("deleted vector 'a' still reachable from search")
+		}
+	}
+
+	// Deleting 'a' pushed the tombstone ratio to 1/2, reaching the 0.5
+	// threshold, so a background compact() should have been kicked off -
+	// give it a moment to finish.
+	deadline := time.Now().Add(time.Second)
+	for idx.TombstoneCount() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := idx.TombstoneCount(); got != 0 {
+		t.Fatalf("expected compaction to clear the tombstone, got TombstoneCount() = %d", got)
+	}
+
+	// Re-inserting the same ID after compaction behaves like a fresh insert.
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	if !idx.Exists("a") {
+		t.Error("expected 'a' to exist again after being reinserted")
+	}
+}
+
+// TestHNSWSaveLoad verifies a saved graph can be restored with the same
+// vector count and still returns the nearest vector among its results.
+func TestHNSWSaveLoad(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewHNSWIndex(config)
+
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0}})
+	idx.Insert(types.Vector{ID: "b", Embedding: []float32{0, 1, 0}})
+	idx.Insert(types.Vector{ID: "c", Embedding: []float32{0.9, 0.1, 0}})
+
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	restored := index.NewHNSWIndex(index.DefaultHNSWConfig(3))
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if restored.Count() != idx.Count() {
+		t.Fatalf("expected %d vectors after restore, got %d", idx.Count(), restored.Count())
+	}
+
+	results := restored.Search([]float32{1, 0, 0}, 3)
+	if !containsID(results, "a") {
+		t.Errorf("expected restored index to return 'a' among results, got %+v", results)
+	}
+}
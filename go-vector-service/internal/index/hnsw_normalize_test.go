@@ -0,0 +1,55 @@
+package index_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestHNSWNormalizeOnInsertMatchesUnnormalized checks that enabling
+// NormalizeOnInsert doesn't change search results, since cosine similarity
+// is scale-invariant — it only changes how the comparisons are computed
+// internally (a single SIMD dot product instead of two extra norms).
+func TestHNSWNormalizeOnInsertMatchesUnnormalized(t *testing.T) {
+	vectors := []types.Vector{
+		{ID: "a", Embedding: []float32{3, 0, 0}},
+		{ID: "b", Embedding: []float32{0, 2, 0}},
+		{ID: "c", Embedding: []float32{2.7, 0.3, 0}},
+	}
+
+	plain := index.NewHNSWIndex(index.DefaultHNSWConfig(3))
+	normalized := index.NewHNSWIndex(func() index.HNSWConfig {
+		c := index.DefaultHNSWConfig(3)
+		c.NormalizeOnInsert = true
+		return c
+	}())
+
+	for _, v := range vectors {
+		plain.Insert(v)
+		normalized.Insert(v)
+	}
+
+	query := []float32{1, 0, 0}
+	plainResults := plain.Search(query, 3)
+	normResults := normalized.Search(query, 3)
+
+	if len(plainResults) != len(normResults) {
+		t.Fatalf("result count mismatch: plain=%d normalized=%d", len(plainResults), len(normResults))
+	}
+
+	scores := make(map[string]float32, len(plainResults))
+	for _, r := range plainResults {
+		scores[r.ID] = r.Score
+	}
+	for _, r := range normResults {
+		want, ok := scores[r.ID]
+		if !ok {
+			t.Fatalf("id %q present in normalized results but not plain results", r.ID)
+		}
+		if math.Abs(float64(want-r.Score)) > 1e-4 {
+			t.Errorf("score mismatch for %q: plain=%v normalized=%v", r.ID, want, r.Score)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/documind/vector-service/pkg/quantize"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// pqNode mirrors HNSWNode but stores a PQ code in place of the full
+// []float32 embedding.
+type pqNode struct {
+	ID        string
+	Code      []uint8
+	Metadata  types.Metadata
+	Neighbors [][]string
+	Layer     int
+}
+
+// HNSWPQIndex is an HNSW graph built over Product-Quantization-compressed
+// vectors (see pkg/quantize): every node stores an M-byte PQ code instead
+// of its D*4-byte []float32 embedding, trading some recall for a large
+// memory-footprint reduction. The graph algorithm itself - layered greedy
+// descent, beam search, neighbor pruning - is identical to HNSWIndex; only
+// the distance function changes, since it now operates on codes.
+//
+// Unlike HNSWIndex, construction here isn't fine-grained-locked: PQ
+// indexes are meant to be bulk-trained-then-loaded rather than built
+// incrementally under heavy concurrent write load, so a single coarse
+// mutex keeps this implementation simple.
+type HNSWPQIndex struct {
+	nodes      map[string]*pqNode
+	entryPoint string
+	maxLevel   int
+	config     HNSWConfig
+	codec      *quantize.PQ
+	mu         sync.RWMutex
+	rng        *rand.Rand
+}
+
+// NewHNSWPQIndex creates an HNSW-PQ index using an already-trained codec.
+// Returns an error if codec hasn't been trained yet, since Insert would
+// otherwise encode every vector against empty codebooks.
+func NewHNSWPQIndex(config HNSWConfig, codec *quantize.PQ) (*HNSWPQIndex, error) {
+	if !codec.Trained() {
+		return nil, fmt.Errorf("hnsw-pq: codec must be trained before building an index")
+	}
+	return &HNSWPQIndex{
+		nodes:    make(map[string]*pqNode),
+		maxLevel: -1,
+		config:   config,
+		codec:    codec,
+		rng:      rand.New(rand.NewSource(42)),
+	}, nil
+}
+
+// codeDistance computes the squared Euclidean distance between two codes by
+// decoding both back to approximate vectors. Used during graph
+// construction, where there's no query vector to build a DistanceTable
+// against - only two already-quantized nodes.
+func (idx *HNSWPQIndex) codeDistance(a, b []uint8) float32 {
+	return l2SquaredVectors(idx.codec.Decode(a), idx.codec.Decode(b))
+}
+
+// l2SquaredVectors computes squared Euclidean distance between two
+// equal-length float32 vectors, matching L2DistanceSquared without the
+// empty/length-mismatch guard, which callers here already satisfy by
+// construction (both sides come from the same codec's Decode).
+func l2SquaredVectors(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+// randomLevel mirrors HNSWIndex.randomLevel; duplicated rather than shared
+// since HNSWPQIndex doesn't hold the per-operation rngMu HNSWIndex uses for
+// concurrent inserts (see the type's doc comment on locking).
+func (idx *HNSWPQIndex) randomLevel() int {
+	level := 0
+	for idx.rng.Float64() < idx.config.ML && level < 16 {
+		level++
+	}
+	return level
+}
+
+// Insert encodes v with the index's PQ codec and adds it to the graph.
+func (idx *HNSWPQIndex) Insert(v types.Vector) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	code := idx.codec.Encode(v.Embedding)
+	level := idx.randomLevel()
+
+	node := &pqNode{
+		ID:        v.ID,
+		Code:      code,
+		Metadata:  v.Metadata,
+		Neighbors: make([][]string, level+1),
+		Layer:     level,
+	}
+	for i := range node.Neighbors {
+		node.Neighbors[i] = make([]string, 0)
+	}
+	idx.nodes[v.ID] = node
+
+	if idx.entryPoint == "" {
+		idx.entryPoint = v.ID
+		idx.maxLevel = level
+		return nil
+	}
+
+	entryID := idx.entryPoint
+	currentNode := idx.nodes[entryID]
+
+	for l := idx.maxLevel; l > level; l-- {
+		changed := true
+		for changed {
+			changed = false
+			for _, neighborID := range currentNode.Neighbors[l] {
+				neighbor := idx.nodes[neighborID]
+				if neighbor == nil {
+					continue
+				}
+				if idx.codeDistance(code, neighbor.Code) < idx.codeDistance(code, currentNode.Code) {
+					currentNode = neighbor
+					entryID = neighborID
+					changed = true
+				}
+			}
+		}
+	}
+
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		candidates := idx.searchLayerByCode(code, entryID, idx.config.EfConstruction, l)
+
+		mLayer := idx.config.M
+		if l == 0 {
+			mLayer = idx.config.MMax
+		}
+		if len(candidates) > mLayer {
+			candidates = candidates[:mLayer]
+		}
+
+		for _, c := range candidates {
+			neighbor := idx.nodes[c.ID]
+			if neighbor == nil {
+				continue
+			}
+			idx.linkPQ(l, mLayer, node, neighbor)
+		}
+
+		if len(candidates) > 0 {
+			entryID = candidates[0].ID
+		}
+	}
+
+	if level > idx.maxLevel {
+		idx.entryPoint = v.ID
+		idx.maxLevel = level
+	}
+
+	return nil
+}
+
+// linkPQ adds a to b's neighbor list and b to a's, pruning either side back
+// to mLayer by code distance if it overflows. Layer 0 is exempt from
+// pruning for the same reachability reason as HNSWIndex.addLocked.
+func (idx *HNSWPQIndex) linkPQ(layer, mLayer int, a, b *pqNode) {
+	addPQ := func(n, other *pqNode) {
+		if layer >= len(n.Neighbors) {
+			return
+		}
+		n.Neighbors[layer] = append(n.Neighbors[layer], other.ID)
+		if layer == 0 || len(n.Neighbors[layer]) <= mLayer {
+			return
+		}
+		n.Neighbors[layer] = idx.prunePQ(n.Code, n.Neighbors[layer], mLayer)
+	}
+	addPQ(a, b)
+	addPQ(b, a)
+}
+
+// prunePQ keeps the m neighbors closest to nodeCode by code distance.
+func (idx *HNSWPQIndex) prunePQ(nodeCode []uint8, neighbors []string, m int) []string {
+	if len(neighbors) <= m {
+		return neighbors
+	}
+	candidates := make([]distanceNode, 0, len(neighbors))
+	for _, nid := range neighbors {
+		n := idx.nodes[nid]
+		if n != nil {
+			candidates = append(candidates, distanceNode{ID: nid, Distance: idx.codeDistance(nodeCode, n.Code)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Distance < candidates[j].Distance })
+	if len(candidates) > m {
+		candidates = candidates[:m]
+	}
+	result := make([]string, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.ID
+	}
+	return result
+}
+
+// searchLayerByCode is searchLayer's code-distance analogue, used during
+// construction where neighbors are scored against another node's code
+// rather than a query vector.
+func (idx *HNSWPQIndex) searchLayerByCode(code []uint8, entryID string, ef int, layer int) []distanceNode {
+	return idx.beamSearch(entryID, ef, layer, func(n *pqNode) float32 {
+		return idx.codeDistance(code, n.Code)
+	})
+}
+
+// beamSearch is the shared traversal behind searchLayerByCode and Search: a
+// greedy beam search over the graph at layer, scoring each visited node
+// with score. Factored out so Search can score with ADC against a
+// precomputed DistanceTable instead of decoding codes.
+//
+// Unlike HNSWIndex.searchLayer this tracks the candidate/result sets as
+// plain slices with a linear scan for the min/max each step, rather than a
+// pair of heaps - ef is small (tens to low hundreds) and this index isn't
+// on the fine-grained-concurrency path HNSWIndex is, so the simpler
+// implementation is worth the mild constant-factor cost.
+func (idx *HNSWPQIndex) beamSearch(entryID string, ef int, layer int, score func(*pqNode) float32) []distanceNode {
+	entryNode := idx.nodes[entryID]
+	if entryNode == nil {
+		return nil
+	}
+
+	visited := map[string]bool{entryID: true}
+	entryDist := score(entryNode)
+	candidates := []distanceNode{{ID: entryID, Distance: entryDist}}
+	results := []distanceNode{{ID: entryID, Distance: entryDist}}
+
+	for len(candidates) > 0 {
+		closestIdx := 0
+		for i, c := range candidates {
+			if c.Distance < candidates[closestIdx].Distance {
+				closestIdx = i
+			}
+		}
+		closest := candidates[closestIdx]
+		candidates = append(candidates[:closestIdx], candidates[closestIdx+1:]...)
+
+		worst := results[0].Distance
+		for _, r := range results {
+			if r.Distance > worst {
+				worst = r.Distance
+			}
+		}
+		if len(results) >= ef && closest.Distance > worst {
+			break
+		}
+
+		node := idx.nodes[closest.ID]
+		if node == nil || layer >= len(node.Neighbors) {
+			continue
+		}
+
+		for _, neighborID := range node.Neighbors[layer] {
+			if visited[neighborID] {
+				continue
+			}
+			visited[neighborID] = true
+
+			neighbor := idx.nodes[neighborID]
+			if neighbor == nil {
+				continue
+			}
+
+			dist := score(neighbor)
+			if len(results) < ef {
+				candidates = append(candidates, distanceNode{ID: neighborID, Distance: dist})
+				results = append(results, distanceNode{ID: neighborID, Distance: dist})
+				continue
+			}
+
+			worstIdx := 0
+			for i, r := range results {
+				if r.Distance > results[worstIdx].Distance {
+					worstIdx = i
+				}
+			}
+			if dist < results[worstIdx].Distance {
+				candidates = append(candidates, distanceNode{ID: neighborID, Distance: dist})
+				results[worstIdx] = distanceNode{ID: neighborID, Distance: dist}
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	return results
+}
+
+// Search finds the top-k approximate nearest neighbors to query. A single
+// DistanceTable is built for query up front (see pkg/quantize.PQ), so every
+// comparison during the graph walk is M table lookups summed (ADC) instead
+// of a full distance computation against a decoded vector.
+func (idx *HNSWPQIndex) Search(query []float32, topK int) []types.SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.nodes) == 0 || idx.entryPoint == "" {
+		return []types.SearchResult{}
+	}
+
+	table := idx.codec.DistanceTable(query)
+	score := func(n *pqNode) float32 { return quantize.ADC(table, n.Code) }
+
+	entryID := idx.entryPoint
+	currentNode := idx.nodes[entryID]
+
+	for l := idx.maxLevel; l > 0; l-- {
+		changed := true
+		for changed {
+			changed = false
+			for _, neighborID := range currentNode.Neighbors[l] {
+				neighbor := idx.nodes[neighborID]
+				if neighbor == nil {
+					continue
+				}
+				if score(neighbor) < score(currentNode) {
+					currentNode = neighbor
+					entryID = neighborID
+					changed = true
+				}
+			}
+		}
+	}
+
+	candidates := idx.beamSearch(entryID, idx.config.EfSearch, 0, score)
+
+	results := make([]types.SearchResult, 0, topK)
+	for i := 0; i < len(candidates) && i < topK; i++ {
+		node := idx.nodes[candidates[i].ID]
+		if node != nil {
+			results = append(results, types.SearchResult{
+				ID:       candidates[i].ID,
+				Score:    -candidates[i].Distance, // higher = closer, consistent with the Distance interface's Similarity convention
+				Metadata: node.Metadata,
+			})
+		}
+	}
+	return results
+}
+
+// Count returns the number of vectors in the index.
+func (idx *HNSWPQIndex) Count() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.nodes)
+}
+
+// MemoryPerVector returns the approximate number of bytes each stored
+// vector occupies: its PQ code, versus the []float32 embedding an
+// unquantized HNSWIndex would store for it. Used by /stats to report the
+// compression ratio.
+func (idx *HNSWPQIndex) MemoryPerVector() (pqBytes, uncompressedBytes int) {
+	return idx.codec.CodeSize(), idx.codec.Dim * 4
+}
@@ -0,0 +1,88 @@
+// Tests for HNSWPQIndex, the PQ-compressed HNSW variant.
+package index_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/quantize"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+func trainedPQ(t *testing.T, dim, m, k, n int) *quantize.PQ {
+	t.Helper()
+	pq, err := quantize.NewPQ(dim, m, k)
+	if err != nil {
+		t.Fatalf("NewPQ returned error: %v", err)
+	}
+	training := make([][]float32, n)
+	for i := range training {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rand.Float32()*2 - 1
+		}
+		training[i] = v
+	}
+	if err := pq.Train(training, 5); err != nil {
+		t.Fatalf("Train returned error: %v", err)
+	}
+	return pq
+}
+
+// TestHNSWPQRequiresTrainedCodec checks NewHNSWPQIndex refuses an untrained
+// codec rather than silently quantizing against empty codebooks.
+func TestHNSWPQRequiresTrainedCodec(t *testing.T) {
+	pq, err := quantize.NewPQ(8, 2, 16)
+	if err != nil {
+		t.Fatalf("NewPQ returned error: %v", err)
+	}
+	if _, err := index.NewHNSWPQIndex(index.DefaultHNSWConfig(8), pq); err == nil {
+		t.Error("expected NewHNSWPQIndex to reject an untrained codec")
+	}
+}
+
+// TestHNSWPQSearchFindsNearest checks a small, well-separated dataset still
+// surfaces the true nearest neighbor despite quantization.
+func TestHNSWPQSearchFindsNearest(t *testing.T) {
+	pq := trainedPQ(t, 8, 2, 16, 200)
+
+	idx, err := index.NewHNSWPQIndex(index.DefaultHNSWConfig(8), pq)
+	if err != nil {
+		t.Fatalf("NewHNSWPQIndex returned error: %v", err)
+	}
+
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0, 0, 0, 0, 0, 0}})
+	idx.Insert(types.Vector{ID: "b", Embedding: []float32{0, 0, 0, 0, 0, 0, 0, 1}})
+	idx.Insert(types.Vector{ID: "c", Embedding: []float32{0.9, 0.05, 0, 0, 0, 0, 0, 0}})
+
+	results := idx.Search([]float32{1, 0, 0, 0, 0, 0, 0, 0}, 1)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].ID != "a" && results[0].ID != "c" {
+		t.Errorf("expected nearest neighbor 'a' or 'c', got %q", results[0].ID)
+	}
+}
+
+// TestHNSWPQMemoryPerVector checks the reported PQ code size is far smaller
+// than the uncompressed float32 embedding it replaces.
+func TestHNSWPQMemoryPerVector(t *testing.T) {
+	pq := trainedPQ(t, 128, 16, 16, 200)
+
+	idx, err := index.NewHNSWPQIndex(index.DefaultHNSWConfig(128), pq)
+	if err != nil {
+		t.Fatalf("NewHNSWPQIndex returned error: %v", err)
+	}
+
+	pqBytes, uncompressedBytes := idx.MemoryPerVector()
+	if pqBytes != 16 {
+		t.Errorf("expected 16 bytes per PQ code, got %d", pqBytes)
+	}
+	if uncompressedBytes != 128*4 {
+		t.Errorf("expected %d uncompressed bytes, got %d", 128*4, uncompressedBytes)
+	}
+	if pqBytes >= uncompressedBytes {
+		t.Errorf("expected PQ encoding to be smaller than uncompressed: %d vs %d", pqBytes, uncompressedBytes)
+	}
+}
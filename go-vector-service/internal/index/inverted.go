@@ -0,0 +1,127 @@
+package index
+
+import (
+	"sync"
+
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// invertedIndex keeps posting lists of vector IDs keyed by document_id and
+// by tag key/value, so a Filter's equality clauses can be answered without
+// scanning every vector - the same role a tablet/predicate index plays
+// alongside a graph-based ANN search.
+type invertedIndex struct {
+	mu      sync.RWMutex
+	byDocID map[string]map[string]bool
+	byTag   map[string]map[string]map[string]bool // tag key -> tag value -> vector IDs
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		byDocID: make(map[string]map[string]bool),
+		byTag:   make(map[string]map[string]map[string]bool),
+	}
+}
+
+// insert adds id's posting-list entries for meta.DocumentID and meta.Tags.
+func (ii *invertedIndex) insert(id string, meta types.Metadata) {
+	ii.mu.Lock()
+	defer ii.mu.Unlock()
+
+	if meta.DocumentID != "" {
+		set := ii.byDocID[meta.DocumentID]
+		if set == nil {
+			set = make(map[string]bool)
+			ii.byDocID[meta.DocumentID] = set
+		}
+		set[id] = true
+	}
+	for k, v := range meta.Tags {
+		vals := ii.byTag[k]
+		if vals == nil {
+			vals = make(map[string]map[string]bool)
+			ii.byTag[k] = vals
+		}
+		set := vals[v]
+		if set == nil {
+			set = make(map[string]bool)
+			vals[v] = set
+		}
+		set[id] = true
+	}
+}
+
+// remove drops id from the posting lists it was registered under by insert.
+func (ii *invertedIndex) remove(id string, meta types.Metadata) {
+	ii.mu.Lock()
+	defer ii.mu.Unlock()
+
+	if set := ii.byDocID[meta.DocumentID]; set != nil {
+		delete(set, id)
+		if len(set) == 0 {
+			delete(ii.byDocID, meta.DocumentID)
+		}
+	}
+	for k, v := range meta.Tags {
+		vals := ii.byTag[k]
+		if vals == nil {
+			continue
+		}
+		if set := vals[v]; set != nil {
+			delete(set, id)
+			if len(set) == 0 {
+				delete(vals, v)
+			}
+		}
+		if len(vals) == 0 {
+			delete(ii.byTag, k)
+		}
+	}
+}
+
+// candidateIDs returns the vector IDs satisfying every equality clause on f
+// that the inverted index can answer (DocumentID and Tags), and whether any
+// such clause was present. When false, f has no equality clause to narrow
+// the scan with - e.g. it's nil, or only sets DocumentIDPrefix/PageNumber
+// range/TagsIn clauses - and callers should fall back to scanning every
+// vector themselves.
+func (ii *invertedIndex) candidateIDs(f *types.Filter) (map[string]bool, bool) {
+	if f == nil {
+		return nil, false
+	}
+	ii.mu.RLock()
+	defer ii.mu.RUnlock()
+
+	var result map[string]bool
+	used := false
+	intersect := func(set map[string]bool) {
+		if result == nil {
+			result = make(map[string]bool, len(set))
+			for id := range set {
+				result[id] = true
+			}
+			return
+		}
+		for id := range result {
+			if !set[id] {
+				delete(result, id)
+			}
+		}
+	}
+
+	if f.DocumentID != "" {
+		intersect(ii.byDocID[f.DocumentID])
+		used = true
+	}
+	for k, v := range f.Tags {
+		intersect(ii.byTag[k][v])
+		used = true
+	}
+	if !used {
+		return nil, false
+	}
+	if result == nil {
+		result = make(map[string]bool)
+	}
+	return result, true
+}
@@ -0,0 +1,78 @@
+package index
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// ResultIterator yields a search's results one at a time in descending-score
+// order, so a caller like HandleSearch's NDJSON streaming path can write
+// them out as they're produced instead of holding the whole result set as a
+// second allocation the way Search/SearchFiltered's returned slice does.
+// Callers must call Close exactly once, even if they don't exhaust Next, to
+// return the iterator's pooled buffers.
+type ResultIterator interface {
+	// Next returns the next result and true, or the zero value and false
+	// once exhausted.
+	Next() (types.SearchResult, bool)
+	// Close returns the iterator's backing buffers to their pool.
+	Close()
+}
+
+// resultHeapPool recycles resultHeap backing arrays across SearchIter calls
+// instead of allocating a fresh one on every query the way Search does.
+var resultHeapPool = sync.Pool{
+	New: func() interface{} { return &resultHeap{} },
+}
+
+// resultSlicePool recycles the []types.SearchResult scratch buffer a
+// sliceIterator walks, likewise reused across calls instead of allocated
+// fresh.
+var resultSlicePool = sync.Pool{
+	New: func() interface{} { return make([]types.SearchResult, 0, 16) },
+}
+
+// sliceIterator is the ResultIterator every SearchIter/SearchIterFiltered
+// implementation returns: a position into a pooled, pre-sorted
+// []types.SearchResult.
+type sliceIterator struct {
+	h       *resultHeap // non-nil if results was drained from a pooled heap; returned to resultHeapPool on Close
+	results []types.SearchResult
+	pos     int
+}
+
+func (it *sliceIterator) Next() (types.SearchResult, bool) {
+	if it.pos >= len(it.results) {
+		return types.SearchResult{}, false
+	}
+	r := it.results[it.pos]
+	it.pos++
+	return r, true
+}
+
+func (it *sliceIterator) Close() {
+	if it.h != nil {
+		*it.h = (*it.h)[:0]
+		resultHeapPool.Put(it.h)
+	}
+	resultSlicePool.Put(it.results[:0]) //nolint:staticcheck // intentionally reusing the backing array
+}
+
+// drainHeapPooled pops every result off h in ascending order and reverses
+// them into a pooled scratch slice, returning a sliceIterator over it. h
+// itself is kept so Close can return it to resultHeapPool too.
+func drainHeapPooled(h *resultHeap) *sliceIterator {
+	n := h.Len()
+	results := resultSlicePool.Get().([]types.SearchResult)
+	if cap(results) < n {
+		results = make([]types.SearchResult, n)
+	} else {
+		results = results[:n]
+	}
+	for i := n - 1; i >= 0; i-- {
+		results[i] = heap.Pop(h).(types.SearchResult)
+	}
+	return &sliceIterator{h: h, results: results}
+}
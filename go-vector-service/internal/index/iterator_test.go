@@ -0,0 +1,104 @@
+// Tests for ResultIterator and the pooled SearchIter path.
+package index_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestBruteForceSearchIterMatchesSearch checks that SearchIter yields the
+// same results, in the same order, as Search's slice.
+func TestBruteForceSearchIterMatchesSearch(t *testing.T) {
+	idx := index.NewBruteForceIndex(3)
+	idx.InsertBatch(generateRandomVectors(50, 3))
+	query := generateRandomVector(3)
+
+	want := idx.Search(query, 5)
+
+	it := idx.SearchIter(query, 5)
+	defer it.Close()
+
+	var got []types.SearchResult
+	for {
+		r, ok := it.Next()
+		if !ok {
+			break
+		}
+		got = append(got, r)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Score != want[i].Score {
+			t.Errorf("result %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestHNSWSearchIterMatchesSearch is the same check against HNSWIndex.
+func TestHNSWSearchIterMatchesSearch(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewHNSWIndex(config)
+	idx.InsertBatch(generateRandomVectors(50, 3))
+	query := generateRandomVector(3)
+
+	want := idx.Search(query, 5)
+
+	it := idx.SearchIter(query, 5)
+	defer it.Close()
+
+	var got []types.SearchResult
+	for {
+		r, ok := it.Next()
+		if !ok {
+			break
+		}
+		got = append(got, r)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID {
+			t.Errorf("result %d: got %q, want %q", i, got[i].ID, want[i].ID)
+		}
+	}
+}
+
+// BenchmarkBruteForceSearchIterAllocs compares SearchIter against Search:
+// pooling the resultHeap and result slice cuts the allocations those two
+// buffers would otherwise make on every call, on top of whatever the
+// per-query candidate/score scratch (scaling with corpus size, not topK)
+// already allocates. Run with -benchmem to see allocs/op.
+func BenchmarkBruteForceSearchIterAllocs(b *testing.B) {
+	idx := index.NewBruteForceIndex(dimensions)
+	idx.InsertBatch(generateRandomVectors(1000, dimensions))
+	query := generateRandomVector(dimensions)
+
+	for _, k := range []int{10, 100} {
+		b.Run(fmt.Sprintf("Search/topK=%d", k), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				idx.Search(query, k)
+			}
+		})
+		b.Run(fmt.Sprintf("SearchIter/topK=%d", k), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				it := idx.SearchIter(query, k)
+				for {
+					if _, ok := it.Next(); !ok {
+						break
+					}
+				}
+				it.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Tests for the pluggable Distance metrics and their effect on ranking.
+package index_test
+
+import (
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestL2MetricMatchesCosineRankingOnNormalizedVectors checks that, once
+// vectors are unit-normalized, ranking by squared L2 distance agrees with
+// ranking by cosine similarity - the two metrics order points identically
+// on the unit sphere, just on different scales.
+func TestL2MetricMatchesCosineRankingOnNormalizedVectors(t *testing.T) {
+	vectors := []types.Vector{
+		{ID: "a", Embedding: index.Normalize([]float32{1, 0, 0})},
+		{ID: "b", Embedding: index.Normalize([]float32{0, 1, 0})},
+		{ID: "c", Embedding: index.Normalize([]float32{0.9, 0.1, 0})},
+	}
+	query := index.Normalize([]float32{1, 0, 0})
+
+	cosineIdx := index.NewBruteForceIndex(3)
+	l2Idx := index.NewBruteForceIndexWithMetric(3, index.L2Squared{})
+
+	for _, v := range vectors {
+		cosineIdx.Insert(v)
+		l2Idx.Insert(v)
+	}
+
+	cosineResults := cosineIdx.Search(query, 3)
+	l2Results := l2Idx.Search(query, 3)
+
+	if len(cosineResults) != len(l2Results) {
+		t.Fatalf("result count mismatch: cosine=%d l2=%d", len(cosineResults), len(l2Results))
+	}
+	for i := range cosineResults {
+		if cosineResults[i].ID != l2Results[i].ID {
+			t.Errorf("rank %d mismatch: cosine=%s l2=%s", i, cosineResults[i].ID, l2Results[i].ID)
+		}
+	}
+}
+
+// TestInnerProductSurfacesMagnitudeCosineMisses checks that, on
+// non-normalized embeddings, inner-product mode ranks a much longer vector
+// angled slightly away from the query above a short vector pointing exactly
+// along it - a result cosine, which is scale-invariant, can never produce.
+func TestInnerProductSurfacesMagnitudeCosineMisses(t *testing.T) {
+	query := []float32{1, 0, 0}
+
+	// "short" is angularly identical to the query but tiny in magnitude.
+	// "long" is angled slightly away but scaled way up - its raw dot
+	// product with the query is larger, even though its cosine similarity
+	// is lower.
+	short := types.Vector{ID: "short", Embedding: []float32{0.01, 0, 0}}
+	long := types.Vector{ID: "long", Embedding: []float32{8, 1, 0}}
+
+	cosineIdx := index.NewBruteForceIndex(3)
+	cosineIdx.Insert(short)
+	cosineIdx.Insert(long)
+
+	cosineResults := cosineIdx.Search(query, 1)
+	if len(cosineResults) != 1 || cosineResults[0].ID != "short" {
+		t.Fatalf("expected cosine to rank 'short' first, got %+v", cosineResults)
+	}
+
+	ipIdx := index.NewBruteForceIndexWithMetric(3, index.InnerProduct{})
+	ipIdx.Insert(short)
+	ipIdx.Insert(long)
+
+	ipResults := ipIdx.Search(query, 1)
+	if len(ipResults) != 1 || ipResults[0].ID != "long" {
+		t.Fatalf("expected inner-product to rank 'long' first, got %+v", ipResults)
+	}
+}
@@ -0,0 +1,336 @@
+package index
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// shardVirtualNodes is how many points each shard owns on the consistent-
+// hash ring. More points spread a shard's key range more evenly across the
+// ring at the cost of a larger ring to search on every routing decision.
+const shardVirtualNodes = 150
+
+// hashRing maps a shard key to a shard index via consistent hashing, so
+// growing ShardedIndex's shard count only remaps roughly a 1/N fraction of
+// keys instead of rehashing every vector, the way a plain hash(key)%N would.
+type hashRing struct {
+	points []uint32 // sorted ascending
+	owner  []int    // owner[i] is the shard that owns points[i]
+}
+
+func newHashRing(numShards int) *hashRing {
+	r := &hashRing{}
+	for shard := 0; shard < numShards; shard++ {
+		for v := 0; v < shardVirtualNodes; v++ {
+			r.points = append(r.points, ringHash(fmt.Sprintf("shard-%d-vnode-%d", shard, v)))
+			r.owner = append(r.owner, shard)
+		}
+	}
+	sort.Sort(r)
+	return r
+}
+
+func ringHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func (r *hashRing) Len() int { return len(r.points) }
+func (r *hashRing) Swap(i, j int) {
+	r.points[i], r.points[j] = r.points[j], r.points[i]
+	r.owner[i], r.owner[j] = r.owner[j], r.owner[i]
+}
+func (r *hashRing) Less(i, j int) bool { return r.points[i] < r.points[j] }
+
+// shardFor returns the shard index key routes to: the owner of the first
+// ring point at or past key's hash, wrapping around to the first point if
+// key's hash is past every point on the ring.
+func (r *hashRing) shardFor(key string) int {
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owner[i]
+}
+
+// shardLatencySamples bounds how many recent Search latencies each shard
+// keeps for its p50/p99 estimate, so the sample never grows unbounded.
+const shardLatencySamples = 256
+
+// shard pairs a BruteForceIndex and HNSWIndex - the same pair Handler keeps
+// for the unsharded default - with a rolling latency sample used for
+// ShardedIndex.Stats.
+type shard struct {
+	bruteForce *BruteForceIndex
+	hnsw       *HNSWIndex
+
+	latMu     sync.Mutex
+	latencies []time.Duration
+}
+
+func (s *shard) recordLatency(d time.Duration) {
+	s.latMu.Lock()
+	defer s.latMu.Unlock()
+	s.latencies = append(s.latencies, d)
+	if over := len(s.latencies) - shardLatencySamples; over > 0 {
+		s.latencies = s.latencies[over:]
+	}
+}
+
+// percentiles returns the p50 and p99 of the shard's recent Search
+// latencies, or (0, 0) if it hasn't served a search yet.
+func (s *shard) percentiles() (p50, p99 time.Duration) {
+	s.latMu.Lock()
+	defer s.latMu.Unlock()
+	if len(s.latencies) == 0 {
+		return 0, 0
+	}
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	pick := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return pick(0.5), pick(0.99)
+}
+
+// ShardStats reports one shard's size, recent search-latency percentiles,
+// and an approximate in-memory footprint, as returned by
+// ShardedIndex.Stats.
+type ShardStats struct {
+	VectorCount int
+	P50Latency  time.Duration
+	P99Latency  time.Duration
+	MemoryBytes int64
+}
+
+// ShardedIndex routes vectors across N BruteForceIndex+HNSWIndex pairs by
+// consistent-hashing a shard key derived from Vector.Metadata (TenantID if
+// set, else DocumentID). This keeps a single hot tenant's writes and
+// searches confined to one shard instead of contending with every other
+// tenant's on one index pair, and lets shard count grow without rehashing
+// every existing vector - the same tenant/label-based sharding pattern
+// distributed profiling and log systems use to scale writes.
+type ShardedIndex struct {
+	shards     []*shard
+	ring       *hashRing
+	dimensions int
+
+	idMu      sync.RWMutex
+	idToShard map[string]int // which shard Insert routed each vector ID to
+}
+
+// NewShardedIndex creates a ShardedIndex of numShards shards (at least 1),
+// each an HNSWIndex built from hnswConfig paired with a BruteForceIndex
+// using the same metric and dimensions.
+func NewShardedIndex(numShards int, hnswConfig HNSWConfig) *ShardedIndex {
+	if numShards < 1 {
+		numShards = 1
+	}
+	metric := hnswConfig.Metric
+	if metric == nil {
+		metric = Cosine{}
+	}
+
+	shards := make([]*shard, numShards)
+	for i := range shards {
+		shards[i] = &shard{
+			bruteForce: NewBruteForceIndexWithMetric(hnswConfig.Dimensions, metric),
+			hnsw:       NewHNSWIndex(hnswConfig),
+		}
+	}
+
+	return &ShardedIndex{
+		shards:     shards,
+		ring:       newHashRing(numShards),
+		dimensions: hnswConfig.Dimensions,
+		idToShard:  make(map[string]int),
+	}
+}
+
+// ShardKey returns the consistent-hash routing key for a vector's metadata:
+// TenantID if set, else DocumentID. Vectors with neither set all land on
+// whichever shard the empty key hashes to.
+func ShardKey(m types.Metadata) string {
+	if m.TenantID != "" {
+		return m.TenantID
+	}
+	return m.DocumentID
+}
+
+// NumShards returns how many shards the index was built with.
+func (idx *ShardedIndex) NumShards() int {
+	return len(idx.shards)
+}
+
+// Insert routes v to the shard its ShardKey hashes to.
+func (idx *ShardedIndex) Insert(v types.Vector) error {
+	shardIdx := idx.ring.shardFor(ShardKey(v.Metadata))
+	s := idx.shards[shardIdx]
+
+	if err := s.bruteForce.Insert(v); err != nil {
+		return err
+	}
+	if err := s.hnsw.Insert(v); err != nil {
+		return err
+	}
+
+	idx.idMu.Lock()
+	idx.idToShard[v.ID] = shardIdx
+	idx.idMu.Unlock()
+	return nil
+}
+
+// InsertBatch inserts each vector, routing it independently by its own
+// ShardKey - a batch isn't required to share a tenant or document.
+func (idx *ShardedIndex) InsertBatch(vectors []types.Vector) (int, error) {
+	for i, v := range vectors {
+		if err := idx.Insert(v); err != nil {
+			return i, err
+		}
+	}
+	return len(vectors), nil
+}
+
+// Delete removes the vector with the given ID from whichever shard Insert
+// routed it to.
+func (idx *ShardedIndex) Delete(id string) error {
+	idx.idMu.Lock()
+	shardIdx, ok := idx.idToShard[id]
+	if ok {
+		delete(idx.idToShard, id)
+	}
+	idx.idMu.Unlock()
+	if !ok {
+		return fmt.Errorf("sharded: vector %q not found", id)
+	}
+
+	s := idx.shards[shardIdx]
+	if err := s.bruteForce.Delete(id); err != nil {
+		return err
+	}
+	return s.hnsw.Delete(id)
+}
+
+// Exists reports whether a vector with the given ID is currently in the
+// index.
+func (idx *ShardedIndex) Exists(id string) bool {
+	idx.idMu.RLock()
+	defer idx.idMu.RUnlock()
+	_, ok := idx.idToShard[id]
+	return ok
+}
+
+// Upsert replaces the vector with v.ID if it exists, or inserts v as new
+// otherwise. Like Delete, finding an existing entry relies on idToShard
+// rather than v.Metadata, so an upsert that changes a vector's TenantID
+// (and therefore which shard Insert would route it to) still finds and
+// removes the old copy before inserting on the new shard.
+func (idx *ShardedIndex) Upsert(v types.Vector) error {
+	idx.idMu.RLock()
+	_, exists := idx.idToShard[v.ID]
+	idx.idMu.RUnlock()
+	if exists {
+		if err := idx.Delete(v.ID); err != nil {
+			return err
+		}
+	}
+	return idx.Insert(v)
+}
+
+// Count returns the total number of vectors across every shard.
+func (idx *ShardedIndex) Count() int {
+	total := 0
+	for _, s := range idx.shards {
+		total += s.bruteForce.Count()
+	}
+	return total
+}
+
+// Dimensions returns the dimensionality of vectors in the index.
+func (idx *ShardedIndex) Dimensions() int {
+	return idx.dimensions
+}
+
+// TombstoneCount returns the total number of deleted-but-not-yet-compacted
+// entries across every shard's brute-force index.
+func (idx *ShardedIndex) TombstoneCount() int {
+	total := 0
+	for _, s := range idx.shards {
+		total += s.bruteForce.TombstoneCount()
+	}
+	return total
+}
+
+// targetShards returns the shard indexes a Search with the given filter
+// should scatter to: just the one shard ShardKey routes to when filter
+// pins a single tenant or document, or every shard otherwise (unfiltered,
+// or filtered only on clauses - tags, page range, document prefix - that
+// don't identify a single shard key).
+func (idx *ShardedIndex) targetShards(filter *types.Filter) []int {
+	key := ""
+	switch {
+	case filter != nil && filter.TenantID != "":
+		key = filter.TenantID
+	case filter != nil && filter.DocumentID != "":
+		key = filter.DocumentID
+	default:
+		all := make([]int, len(idx.shards))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	return []int{idx.ring.shardFor(key)}
+}
+
+// Search scatters query to every shard targetShards(filter) selects,
+// searches each one's HNSW index concurrently with the same filter, and
+// merges their per-shard top-k results into one overall top-k with the same
+// min-heap mergeResults already uses for BruteForceIndex.SearchConcurrent.
+func (idx *ShardedIndex) Search(query []float32, topK int, filter *types.Filter) []types.SearchResult {
+	targets := idx.targetShards(filter)
+
+	resultsChan := make(chan []types.SearchResult, len(targets))
+	var wg sync.WaitGroup
+	for _, shardIdx := range targets {
+		wg.Add(1)
+		go func(s *shard) {
+			defer wg.Done()
+			start := time.Now()
+			results := s.hnsw.SearchFiltered(query, topK, filter)
+			s.recordLatency(time.Since(start))
+			resultsChan <- results
+		}(idx.shards[shardIdx])
+	}
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	return mergeResults(resultsChan, topK)
+}
+
+// Stats returns one ShardStats per shard, in shard order.
+func (idx *ShardedIndex) Stats() []ShardStats {
+	stats := make([]ShardStats, len(idx.shards))
+	for i, s := range idx.shards {
+		p50, p99 := s.percentiles()
+		count := s.bruteForce.Count()
+		stats[i] = ShardStats{
+			VectorCount: count,
+			P50Latency:  p50,
+			P99Latency:  p99,
+			// Rough estimate: each vector's float32 embedding is held once
+			// by BruteForceIndex and again by HNSWIndex.
+			MemoryBytes: int64(count) * int64(idx.dimensions) * 4 * 2,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,120 @@
+// Tests for ShardedIndex's consistent-hash routing and scatter-gather search.
+package index_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/documind/vector-service/internal/index"
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// TestShardedIndexRoutesByTenantThenDocument checks ShardKey's precedence:
+// TenantID wins over DocumentID when both are set, and a vector with
+// neither lands on a shard too (the zero-value key).
+func TestShardedIndexRoutesByTenantThenDocument(t *testing.T) {
+	cases := []struct {
+		meta types.Metadata
+		want string
+	}{
+		{types.Metadata{TenantID: "t1", DocumentID: "d1"}, "t1"},
+		{types.Metadata{DocumentID: "d1"}, "d1"},
+		{types.Metadata{}, ""},
+	}
+	for _, c := range cases {
+		if got := index.ShardKey(c.meta); got != c.want {
+			t.Errorf("ShardKey(%+v) = %q, want %q", c.meta, got, c.want)
+		}
+	}
+}
+
+// TestShardedIndexInsertAndSearchAcrossShards checks that vectors routed to
+// different shards are all still found by an unfiltered Search, which must
+// scatter to every shard and merge results.
+func TestShardedIndexInsertAndSearchAcrossShards(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewShardedIndex(4, config)
+
+	for i := 0; i < 20; i++ {
+		v := types.Vector{
+			ID:        fmt.Sprintf("v%d", i),
+			Embedding: []float32{1, float32(i) * 0.01, 0},
+			Metadata:  types.Metadata{TenantID: fmt.Sprintf("tenant-%d", i%4)},
+		}
+		if err := idx.Insert(v); err != nil {
+			t.Fatalf("insert %q: %v", v.ID, err)
+		}
+	}
+
+	if got := idx.Count(); got != 20 {
+		t.Fatalf("expected 20 vectors total across shards, got %d", got)
+	}
+
+	results := idx.Search([]float32{1, 0, 0}, 5, nil)
+	if len(results) != 5 {
+		t.Fatalf("expected unfiltered search to merge 5 results across shards, got %d: %+v", len(results), results)
+	}
+}
+
+// TestShardedIndexSearchScopedByTenantFilter checks that a Filter pinning a
+// single TenantID only surfaces vectors from that tenant, by scattering to
+// a single shard instead of all of them.
+func TestShardedIndexSearchScopedByTenantFilter(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewShardedIndex(4, config)
+
+	idx.Insert(types.Vector{ID: "a", Embedding: []float32{1, 0, 0}, Metadata: types.Metadata{TenantID: "acme"}})
+	idx.Insert(types.Vector{ID: "b", Embedding: []float32{0.99, 0.01, 0}, Metadata: types.Metadata{TenantID: "globex"}})
+
+	results := idx.Search([]float32{1, 0, 0}, 10, &types.Filter{TenantID: "acme"})
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Fatalf("expected only acme's vector 'a', got %+v", results)
+	}
+}
+
+// TestShardedIndexDeleteRemovesFromOwningShard checks that Delete finds a
+// vector by ID without needing its metadata, and that it's gone afterward.
+func TestShardedIndexDeleteRemovesFromOwningShard(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewShardedIndex(3, config)
+
+	v := types.Vector{ID: "a", Embedding: []float32{1, 0, 0}, Metadata: types.Metadata{DocumentID: "doc-1"}}
+	idx.Insert(v)
+
+	if err := idx.Delete("a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got := idx.Count(); got != 0 {
+		t.Fatalf("expected 0 vectors after delete, got %d", got)
+	}
+	if err := idx.Delete("a"); err == nil {
+		t.Fatal("expected deleting an already-removed vector to error")
+	}
+}
+
+// TestShardedIndexStatsReportsPerShardCounts checks that Stats sums to the
+// same total Count reports, split across the shards vectors were routed to.
+func TestShardedIndexStatsReportsPerShardCounts(t *testing.T) {
+	config := index.DefaultHNSWConfig(3)
+	idx := index.NewShardedIndex(4, config)
+
+	for i := 0; i < 12; i++ {
+		idx.Insert(types.Vector{
+			ID:        fmt.Sprintf("v%d", i),
+			Embedding: []float32{1, float32(i) * 0.01, 0},
+			Metadata:  types.Metadata{TenantID: fmt.Sprintf("tenant-%d", i)},
+		})
+	}
+
+	stats := idx.Stats()
+	if len(stats) != 4 {
+		t.Fatalf("expected 4 shard stats entries, got %d", len(stats))
+	}
+	total := 0
+	for _, s := range stats {
+		total += s.VectorCount
+	}
+	if total != 12 {
+		t.Errorf("expected shard stats to sum to 12 vectors, got %d", total)
+	}
+}
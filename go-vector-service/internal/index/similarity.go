@@ -5,6 +5,8 @@ package index
 
 import (
 	"math"
+
+	"github.com/documind/vector-service/pkg/simd"
 )
 
 // CosineSimilarity calculates the cosine similarity between two vectors.
@@ -13,30 +15,25 @@ import (
 //
 // Formula: cos(θ) = (A · B) / (||A|| × ||B||)
 //
-// Time Complexity: O(n) where n is the vector dimension
-// Space Complexity: O(1)
+// Delegates to pkg/simd, which dispatches to a CPU-specific (AVX2/AVX-512/
+// NEON) kernel at init and falls back to a scalar loop otherwise. This is
+// the hottest function in the package: it runs on every hop of HNSW's
+// searchLayer and on every brute-force comparison.
 func CosineSimilarity(a, b []float32) float32 {
 	if len(a) != len(b) || len(a) == 0 {
 		return 0
 	}
+	return simd.Cosine(a, b)
+}
 
-	var dotProduct float32
-	var normA float32
-	var normB float32
-
-	// Single pass through both vectors for efficiency
-	for i := 0; i < len(a); i++ {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
-	}
-
-	// Avoid division by zero
-	if normA == 0 || normB == 0 {
-		return 0
-	}
-
-	return dotProduct / (float32(math.Sqrt(float64(normA))) * float32(math.Sqrt(float64(normB))))
+// CosineSimilarityBatch scores query against every vector in corpus, writing
+// into out (which must have len(corpus)). Delegates to pkg/simd.CosineBatch,
+// which computes query's norm once for the whole batch instead of
+// recomputing it on every CosineSimilarity call the way a plain loop over
+// corpus would - this is what lets BruteForceIndex.Search amortize the
+// query's load across many candidates.
+func CosineSimilarityBatch(query []float32, corpus [][]float32, out []float32) {
+	simd.CosineBatch(query, corpus, out)
 }
 
 // CosineDistance calculates the cosine distance between two vectors.
@@ -72,35 +69,136 @@ func L2Distance(a, b []float32) float32 {
 // L2DistanceSquared calculates the squared Euclidean distance.
 // This is faster than L2Distance as it avoids the square root.
 // Use this when you only need to compare distances (ranking).
+//
+// Delegates to pkg/simd (see CosineSimilarity).
 func L2DistanceSquared(a, b []float32) float32 {
 	if len(a) != len(b) || len(a) == 0 {
 		return float32(math.MaxFloat32)
 	}
-
-	var sum float32
-	for i := 0; i < len(a); i++ {
-		diff := a[i] - b[i]
-		sum += diff * diff
-	}
-
-	return sum
+	return simd.L2Sq(a, b)
 }
 
 // DotProduct calculates the dot product of two vectors.
 // For normalized vectors, this equals cosine similarity.
 //
 // Formula: A · B = Σ(aᵢ × bᵢ)
+//
+// Delegates to pkg/simd (see CosineSimilarity).
 func DotProduct(a, b []float32) float32 {
 	if len(a) != len(b) || len(a) == 0 {
 		return 0
 	}
+	return simd.Dot(a, b)
+}
 
-	var sum float32
-	for i := 0; i < len(a); i++ {
-		sum += a[i] * b[i]
+// Distance abstracts the scoring function an index builds and searches
+// with, so HNSWIndex and BruteForceIndex aren't hard-wired to cosine.
+// Distance and Similarity are kept as separate methods rather than deriving
+// one from the other because the two don't invert the same way for every
+// metric (e.g. InnerProduct's distance is negated for MIPS ranking, but its
+// similarity is the raw, unnegated dot product callers expect in results).
+type Distance interface {
+	// Distance returns a value where lower means more similar, used for
+	// graph construction, greedy descent, and pruning.
+	Distance(a, b []float32) float32
+	// Similarity returns a value where higher means more similar, used to
+	// score search results.
+	Similarity(a, b []float32) float32
+	// Name identifies the metric, e.g. "cosine", for validating a request's
+	// "metric" field against the index's configured one.
+	Name() string
+}
+
+// BatchDistance is implemented by a Distance that can score a query against
+// many candidates more cheaply together than one at a time (e.g. computing
+// the query's own norm once instead of per candidate). BruteForceIndex.Search
+// uses it when the configured metric implements it, falling back to calling
+// Similarity in a loop otherwise.
+type BatchDistance interface {
+	// SimilarityBatch scores query against every vector in corpus, writing
+	// into out (which must have len(corpus)).
+	SimilarityBatch(query []float32, corpus [][]float32, out []float32)
+}
+
+// Cosine is the default Distance, wrapping CosineDistance/CosineSimilarity.
+type Cosine struct{}
+
+func (Cosine) Distance(a, b []float32) float32   { return CosineDistance(a, b) }
+func (Cosine) Similarity(a, b []float32) float32 { return CosineSimilarity(a, b) }
+func (Cosine) Name() string                      { return "cosine" }
+
+// SimilarityBatch implements BatchDistance.
+func (Cosine) SimilarityBatch(query []float32, corpus [][]float32, out []float32) {
+	CosineSimilarityBatch(query, corpus, out)
+}
+
+// L2Squared ranks by squared Euclidean distance instead of angle, useful
+// when embeddings aren't normalized and absolute magnitude is meaningful.
+type L2Squared struct{}
+
+func (L2Squared) Distance(a, b []float32) float32 { return L2DistanceSquared(a, b) }
+
+// Similarity negates distance so that, like every other metric here, higher
+// still means closer; the result isn't bounded to [-1, 1] like cosine's.
+func (L2Squared) Similarity(a, b []float32) float32 { return -L2DistanceSquared(a, b) }
+func (L2Squared) Name() string                      { return "l2" }
+
+// InnerProduct ranks by raw dot product (maximum inner product search,
+// MIPS), which rewards both direction and magnitude - unlike cosine, a
+// longer vector in the same direction scores higher. Distance negates the
+// dot product since graph construction elsewhere in the package treats
+// lower distance as closer.
+type InnerProduct struct{}
+
+func (InnerProduct) Distance(a, b []float32) float32   { return -DotProduct(a, b) }
+func (InnerProduct) Similarity(a, b []float32) float32 { return DotProduct(a, b) }
+func (InnerProduct) Name() string                      { return "inner_product" }
+
+// Hamming counts the positions at which a and b disagree, treating each
+// element as a single bit (non-zero is 1). DocuMind stores every embedding
+// as []float32 end to end, so this works directly against that
+// representation - a binary embedding is just a Vector.Embedding of 0/1
+// values - rather than introducing a second, packed []uint8 vector type
+// alongside it.
+type Hamming struct{}
+
+func (Hamming) Distance(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return float32(math.MaxFloat32)
+	}
+	var diff float32
+	for i := range a {
+		if (a[i] != 0) != (b[i] != 0) {
+			diff++
+		}
+	}
+	return diff
+}
+
+func (h Hamming) Similarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
 	}
+	return float32(len(a)) - h.Distance(a, b)
+}
 
-	return sum
+func (Hamming) Name() string { return "hamming" }
+
+// DistanceByName resolves a metric name (as sent in a request's "metric"
+// field) to its Distance implementation. Returns false for unknown names.
+func DistanceByName(name string) (Distance, bool) {
+	switch name {
+	case "", "cosine":
+		return Cosine{}, true
+	case "l2":
+		return L2Squared{}, true
+	case "inner_product":
+		return InnerProduct{}, true
+	case "hamming":
+		return Hamming{}, true
+	default:
+		return nil, false
+	}
 }
 
 // Normalize normalizes a vector to unit length (L2 norm = 1).
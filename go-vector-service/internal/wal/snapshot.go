@@ -0,0 +1,141 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snapshotter is the persistence interface every index a WAL snapshot
+// captures must implement - both HNSWIndex and BruteForceIndex satisfy it.
+type Snapshotter interface {
+	Save(w io.Writer) error
+	Load(r io.Reader) error
+}
+
+// snapshotPrefix and snapshotSuffix bound the file names WriteSnapshot
+// produces and FindLatestSnapshot looks for: "snapshot-<seq>.bin".
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".bin"
+)
+
+// SnapshotPath returns the path a snapshot at the given sequence number
+// would be written to within dir.
+func SnapshotPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%d%s", snapshotPrefix, seq, snapshotSuffix))
+}
+
+// WriteSnapshot serializes each part in order to a new file at seq's
+// snapshot path within dir, length-prefixing each part's encoded bytes so
+// ReadSnapshot can decode them back out independently regardless of
+// gob's own framing.
+func WriteSnapshot(dir string, seq uint64, parts ...Snapshotter) error {
+	path := SnapshotPath(dir, seq)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("wal: create snapshot: %w", err)
+	}
+
+	for _, p := range parts {
+		var buf bytes.Buffer
+		if err := p.Save(&buf); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("wal: save snapshot part: %w", err)
+		}
+		if err := binary.Write(f, binary.LittleEndian, uint64(buf.Len())); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("wal: write snapshot part length: %w", err)
+		}
+		if _, err := f.Write(buf.Bytes()); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("wal: write snapshot part: %w", err)
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("wal: sync snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("wal: close snapshot: %w", err)
+	}
+
+	// Rename into place atomically so a crash mid-write never leaves a
+	// partially-written file at the final path for FindLatestSnapshot to
+	// pick up.
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("wal: rename snapshot into place: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot decodes a snapshot previously written by WriteSnapshot at
+// path into parts, in the same order they were saved.
+func ReadSnapshot(path string, parts ...Snapshotter) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("wal: open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	for _, p := range parts {
+		var length uint64
+		if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
+			return fmt.Errorf("wal: read snapshot part length: %w", err)
+		}
+		if err := p.Load(io.LimitReader(f, int64(length))); err != nil {
+			return fmt.Errorf("wal: load snapshot part: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindLatestSnapshot returns the path and sequence number of the
+// highest-numbered snapshot-<seq>.bin file in dir, or ok=false if none
+// exists yet.
+func FindLatestSnapshot(dir string) (path string, seq uint64, ok bool, err error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return "", 0, false, nil
+	}
+	if err != nil {
+		return "", 0, false, fmt.Errorf("wal: read dir: %w", err)
+	}
+
+	var seqs []uint64
+	byPath := make(map[uint64]string)
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, n)
+		byPath[n] = filepath.Join(dir, name)
+	}
+	if len(seqs) == 0 {
+		return "", 0, false, nil
+	}
+
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] > seqs[j] })
+	latest := seqs[0]
+	return byPath[latest], latest, true, nil
+}
@@ -0,0 +1,285 @@
+// Package wal provides a write-ahead log for the vector service. Every
+// Insert/Delete is appended as a length-prefixed gob record to a segment
+// file before being applied to the in-memory indexes, so a crash between
+// those two steps can be recovered from by replaying the log at startup.
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/documind/vector-service/pkg/types"
+)
+
+// FsyncPolicy controls how aggressively the WAL flushes appended records to
+// stable storage.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways fsyncs after every Append, maximizing durability at the
+	// cost of write latency.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval fsyncs on a fixed timer (see Open's interval parameter),
+	// bounding data loss to that window instead of to a single write.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever never explicitly fsyncs, relying on the OS to flush
+	// eventually; fastest, but a crash can lose any unflushed writes.
+	FsyncNever FsyncPolicy = "never"
+)
+
+type opType uint8
+
+const (
+	opInsert opType = iota
+	opDelete
+)
+
+// record is the gob-serializable representation of a single WAL entry.
+type record struct {
+	Seq    uint64
+	Op     opType
+	Vector types.Vector // set when Op == opInsert
+	ID     string       // set when Op == opDelete
+}
+
+// segmentFileName is the name of the WAL's single active segment file
+// within its directory. It's truncated (see Reset) once a snapshot has
+// durably captured everything written to it so far.
+const segmentFileName = "wal.log"
+
+// WAL is an append-only log of Insert/Delete operations, fsynced according
+// to its FsyncPolicy, that can be replayed with Replay to rebuild index
+// state after a crash.
+type WAL struct {
+	path   string
+	file   *os.File
+	mu     sync.Mutex
+	seq    uint64
+	policy FsyncPolicy
+
+	stopTick chan struct{}
+	tickWg   sync.WaitGroup
+}
+
+// Open opens (creating if necessary) dir's WAL segment file for appending.
+// It picks up the sequence counter where any existing log left off, so seq
+// numbers keep increasing across restarts. Open does not replay the log's
+// contents into anything - call Replay first to rebuild index state from a
+// log left behind by a crash.
+func Open(dir string, policy FsyncPolicy, interval time.Duration) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("wal: create dir: %w", err)
+	}
+
+	_, lastSeq, err := Replay(dir, func(types.Vector) error { return nil }, func(string) error { return nil })
+	if err != nil {
+		return nil, fmt.Errorf("wal: scan existing log: %w", err)
+	}
+	// A log emptied by Reset after a snapshot has no records left to carry
+	// the counter, so the latest snapshot's seq is a floor for it too.
+	if _, snapSeq, ok, err := FindLatestSnapshot(dir); err != nil {
+		return nil, err
+	} else if ok && snapSeq > lastSeq {
+		lastSeq = snapSeq
+	}
+
+	path := filepath.Join(dir, segmentFileName)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("wal: open %s: %w", path, err)
+	}
+
+	w := &WAL{
+		path:   path,
+		file:   f,
+		seq:    lastSeq,
+		policy: policy,
+	}
+
+	if policy == FsyncInterval {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		w.stopTick = make(chan struct{})
+		w.tickWg.Add(1)
+		go w.syncLoop(interval)
+	}
+
+	return w, nil
+}
+
+func (w *WAL) syncLoop(interval time.Duration) {
+	defer w.tickWg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			w.file.Sync()
+			w.mu.Unlock()
+		case <-w.stopTick:
+			return
+		}
+	}
+}
+
+// AppendInsert appends an insert record and returns its sequence number.
+func (w *WAL) AppendInsert(v types.Vector) (uint64, error) {
+	return w.append(record{Op: opInsert, Vector: v})
+}
+
+// AppendDelete appends a delete record and returns its sequence number.
+func (w *WAL) AppendDelete(id string) (uint64, error) {
+	return w.append(record{Op: opDelete, ID: id})
+}
+
+func (w *WAL) append(rec record) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seq++
+	rec.Seq = w.seq
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return 0, fmt.Errorf("wal: encode record: %w", err)
+	}
+
+	if err := binary.Write(w.file, binary.LittleEndian, uint64(buf.Len())); err != nil {
+		return 0, fmt.Errorf("wal: write length prefix: %w", err)
+	}
+	if _, err := w.file.Write(buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("wal: write record: %w", err)
+	}
+
+	if w.policy == FsyncAlways {
+		if err := w.file.Sync(); err != nil {
+			return 0, fmt.Errorf("wal: fsync: %w", err)
+		}
+	}
+
+	return rec.Seq, nil
+}
+
+// Seq returns the sequence number of the most recently appended record (0
+// if nothing has been appended since the log was created).
+func (w *WAL) Seq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// Size returns the current size in bytes of the WAL segment file, used to
+// decide when it has grown past a snapshot threshold.
+func (w *WAL) Size() (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info, err := w.file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("wal: stat: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Reset truncates the WAL segment file to empty. Called after a snapshot
+// has durably captured every record written so far, since none of them need
+// replaying again; the sequence counter is left untouched so seq numbers
+// keep increasing across the truncation.
+func (w *WAL) Reset() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("wal: truncate: %w", err)
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("wal: seek: %w", err)
+	}
+	return nil
+}
+
+// Close stops any background fsync timer and closes the segment file.
+func (w *WAL) Close() error {
+	if w.stopTick != nil {
+		close(w.stopTick)
+		w.tickWg.Wait()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Replay reads every record from dir's WAL segment file in order, calling
+// onInsert or onDelete for each, and returns how many records were applied
+// and the highest sequence number seen (0 if the log is empty or doesn't
+// exist yet). It's meant to run once at startup, before Open, to rebuild
+// in-memory index state from a log left behind by a crash.
+func Replay(dir string, onInsert func(types.Vector) error, onDelete func(string) error) (count int, lastSeq uint64, err error) {
+	path := filepath.Join(dir, segmentFileName)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("wal: open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	for {
+		var length uint64
+		if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
+			if err == io.EOF {
+				break
+			}
+			if err == io.ErrUnexpectedEOF {
+				// A kill mid-append can leave a partially-written length
+				// prefix (fewer than 8 bytes). That's the tail of an
+				// in-flight write, not corruption - stop here instead of
+				// failing the whole replay.
+				break
+			}
+			return count, lastSeq, fmt.Errorf("wal: read length prefix: %w", err)
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(f, body); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// The length prefix made it to disk but the record body
+				// didn't (or only partly did) - the same kill-mid-append
+				// tail, just caught one write later. Stop cleanly rather
+				// than treating the torn tail as a corrupt log.
+				break
+			}
+			return count, lastSeq, fmt.Errorf("wal: read record: %w", err)
+		}
+
+		var rec record
+		if err := gob.NewDecoder(bytes.NewReader(body)).Decode(&rec); err != nil {
+			return count, lastSeq, fmt.Errorf("wal: decode record: %w", err)
+		}
+
+		switch rec.Op {
+		case opInsert:
+			if err := onInsert(rec.Vector); err != nil {
+				return count, lastSeq, fmt.Errorf("wal: replay insert: %w", err)
+			}
+		case opDelete:
+			if err := onDelete(rec.ID); err != nil {
+				return count, lastSeq, fmt.Errorf("wal: replay delete: %w", err)
+			}
+		}
+
+		count++
+		lastSeq = rec.Seq
+	}
+
+	return count, lastSeq, nil
+}
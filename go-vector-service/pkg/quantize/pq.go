@@ -0,0 +1,215 @@
+// Package quantize implements Product Quantization (PQ), a lossy vector
+// compression scheme: a D-dimensional vector is split into M equal-length
+// subvectors, each of which is quantized independently against its own
+// K-centroid codebook (learned via k-means), producing an M-byte code in
+// place of the original D*4-byte float32 slice. Distance to a quantized
+// code is then a handful of table lookups (see DistanceTable/ADC) instead
+// of a full dot product, at the cost of some recall.
+package quantize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// PQ is a trained (or trainable) Product Quantization codec for
+// Dim-dimensional vectors.
+type PQ struct {
+	Dim  int
+	M    int // number of subvectors
+	K    int // centroids per subvector codebook; must fit in a uint8 (<=256)
+	DSub int // Dim / M
+
+	// Codebooks[m][k] is the k'th centroid (a DSub-length vector) for the
+	// m'th subvector. Populated by Train.
+	Codebooks [][][]float32
+
+	trained bool
+	rng     *rand.Rand
+}
+
+// NewPQ creates an untrained codec that will split Dim-dimensional vectors
+// into m subvectors, each quantized against a k-centroid codebook.
+func NewPQ(dim, m, k int) (*PQ, error) {
+	if dim <= 0 || m <= 0 || k <= 0 {
+		return nil, fmt.Errorf("quantize: dim, m, and k must all be positive")
+	}
+	if dim%m != 0 {
+		return nil, fmt.Errorf("quantize: dim %d is not divisible by m %d", dim, m)
+	}
+	if k > 256 {
+		return nil, fmt.Errorf("quantize: k must be <= 256 to fit in a uint8 code, got %d", k)
+	}
+	return &PQ{
+		Dim:  dim,
+		M:    m,
+		K:    k,
+		DSub: dim / m,
+		rng:  rand.New(rand.NewSource(42)), // deterministic for reproducibility
+	}, nil
+}
+
+// DefaultPQ creates an untrained codec using the standard k=256 centroids
+// per subvector, so each subvector code fits in a single byte.
+func DefaultPQ(dim, m int) (*PQ, error) {
+	return NewPQ(dim, m, 256)
+}
+
+// Train builds the M codebooks from a sample of training vectors, running
+// iters iterations of Lloyd's algorithm (k-means) independently per
+// subvector. vectors must all have length Dim, and there must be at least K
+// of them (k-means can't seed K distinct centroids from fewer points).
+func (pq *PQ) Train(vectors [][]float32, iters int) error {
+	if len(vectors) < pq.K {
+		return fmt.Errorf("quantize: need at least %d training vectors for k=%d, got %d", pq.K, pq.K, len(vectors))
+	}
+	for _, v := range vectors {
+		if len(v) != pq.Dim {
+			return fmt.Errorf("quantize: training vector has dim %d, want %d", len(v), pq.Dim)
+		}
+	}
+
+	pq.Codebooks = make([][][]float32, pq.M)
+	for m := 0; m < pq.M; m++ {
+		sub := make([][]float32, len(vectors))
+		for i, v := range vectors {
+			sub[i] = v[m*pq.DSub : (m+1)*pq.DSub]
+		}
+		pq.Codebooks[m] = pq.kmeans(sub, iters)
+	}
+	pq.trained = true
+	return nil
+}
+
+// kmeans runs Lloyd's algorithm on data (DSub-dimensional points) for iters
+// iterations and returns the K resulting centroids. Centroids are seeded
+// from K distinct random data points; a centroid that ends up with no
+// assigned points after an iteration is reseeded from a random point so it
+// doesn't collapse into an all-zero vector no code will ever prefer.
+func (pq *PQ) kmeans(data [][]float32, iters int) [][]float32 {
+	centroids := make([][]float32, pq.K)
+	perm := pq.rng.Perm(len(data))
+	for k := 0; k < pq.K; k++ {
+		centroids[k] = append([]float32(nil), data[perm[k%len(perm)]]...)
+	}
+
+	assignments := make([]int, len(data))
+	for iter := 0; iter < iters; iter++ {
+		for i, v := range data {
+			assignments[i] = nearestCentroid(centroids, v)
+		}
+
+		sums := make([][]float32, pq.K)
+		counts := make([]int, pq.K)
+		for k := range sums {
+			sums[k] = make([]float32, pq.DSub)
+		}
+		for i, v := range data {
+			k := assignments[i]
+			counts[k]++
+			for d, val := range v {
+				sums[k][d] += val
+			}
+		}
+
+		for k := 0; k < pq.K; k++ {
+			if counts[k] == 0 {
+				centroids[k] = append([]float32(nil), data[pq.rng.Intn(len(data))]...)
+				continue
+			}
+			for d := range sums[k] {
+				centroids[k][d] = sums[k][d] / float32(counts[k])
+			}
+		}
+	}
+
+	return centroids
+}
+
+// nearestCentroid returns the index of the centroid closest to v by squared
+// Euclidean distance.
+func nearestCentroid(centroids [][]float32, v []float32) int {
+	best, bestDist := 0, float32(math.MaxFloat32)
+	for k, c := range centroids {
+		d := l2Squared(v, c)
+		if d < bestDist {
+			bestDist, best = d, k
+		}
+	}
+	return best
+}
+
+// l2Squared computes the squared Euclidean distance between two equal-length
+// vectors. Kept local to this package rather than imported from
+// internal/index, which itself imports pkg/quantize (for HNSWPQIndex), so
+// importing it back would be an import cycle.
+func l2Squared(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+// Encode quantizes v into an M-byte code, one byte per subvector holding
+// the index of its nearest centroid.
+func (pq *PQ) Encode(v []float32) []uint8 {
+	code := make([]uint8, pq.M)
+	for m := 0; m < pq.M; m++ {
+		sub := v[m*pq.DSub : (m+1)*pq.DSub]
+		code[m] = uint8(nearestCentroid(pq.Codebooks[m], sub))
+	}
+	return code
+}
+
+// Decode reconstructs an approximate vector from a code by concatenating
+// each subvector's chosen centroid. Used where a full vector is needed to
+// compute a distance between two quantized points, e.g. between two
+// stored nodes during graph construction in HNSWPQIndex.
+func (pq *PQ) Decode(code []uint8) []float32 {
+	v := make([]float32, 0, pq.Dim)
+	for m, c := range code {
+		v = append(v, pq.Codebooks[m][c]...)
+	}
+	return v
+}
+
+// DistanceTable precomputes, for query, the squared Euclidean distance from
+// each of its M subvectors to every centroid in that subvector's codebook -
+// an M x K table. Asymmetric Distance Computation (ADC) then scores any
+// code as M table lookups summed (see ADC), instead of decoding the code
+// back into a full vector first.
+func (pq *PQ) DistanceTable(query []float32) [][]float32 {
+	table := make([][]float32, pq.M)
+	for m := 0; m < pq.M; m++ {
+		sub := query[m*pq.DSub : (m+1)*pq.DSub]
+		table[m] = make([]float32, pq.K)
+		for k := 0; k < pq.K; k++ {
+			table[m][k] = l2Squared(sub, pq.Codebooks[m][k])
+		}
+	}
+	return table
+}
+
+// ADC scores code against a table built by DistanceTable: the approximate
+// squared distance from the table's query to code, as M lookups summed.
+func ADC(table [][]float32, code []uint8) float32 {
+	var sum float32
+	for m, c := range code {
+		sum += table[m][c]
+	}
+	return sum
+}
+
+// Trained reports whether Train has been called successfully.
+func (pq *PQ) Trained() bool {
+	return pq.trained
+}
+
+// CodeSize returns the number of bytes a single encoded vector occupies (M),
+// for reporting compression ratios.
+func (pq *PQ) CodeSize() int {
+	return pq.M
+}
@@ -0,0 +1,86 @@
+package quantize
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomVectors(n, dim int) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rand.Float32()*2 - 1
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+// TestPQTrainEncodeRoundTrip checks that encoding a training vector and
+// decoding it back gives a reconstruction reasonably close to the original
+// - the whole point of PQ is a lossy but useful approximation.
+func TestPQTrainEncodeRoundTrip(t *testing.T) {
+	pq, err := NewPQ(16, 4, 16)
+	if err != nil {
+		t.Fatalf("NewPQ returned error: %v", err)
+	}
+
+	vectors := randomVectors(200, 16)
+	if err := pq.Train(vectors, 10); err != nil {
+		t.Fatalf("Train returned error: %v", err)
+	}
+
+	code := pq.Encode(vectors[0])
+	if len(code) != pq.CodeSize() {
+		t.Fatalf("expected code length %d, got %d", pq.CodeSize(), len(code))
+	}
+
+	reconstructed := pq.Decode(code)
+	if d := math.Sqrt(float64(l2Squared(vectors[0], reconstructed))); d > 2.0 {
+		t.Errorf("reconstruction too far from original: l2 distance %v", d)
+	}
+}
+
+// TestPQDistanceTableMatchesDecodedDistance checks that ADC against a
+// DistanceTable agrees with directly computing squared Euclidean distance
+// against the decoded (reconstructed) vector - DistanceTable is just a
+// precomputed form of the same quantity.
+func TestPQDistanceTableMatchesDecodedDistance(t *testing.T) {
+	pq, err := NewPQ(16, 4, 16)
+	if err != nil {
+		t.Fatalf("NewPQ returned error: %v", err)
+	}
+
+	vectors := randomVectors(200, 16)
+	if err := pq.Train(vectors, 10); err != nil {
+		t.Fatalf("Train returned error: %v", err)
+	}
+
+	query := vectors[0]
+	table := pq.DistanceTable(query)
+
+	for _, v := range vectors[1:10] {
+		code := pq.Encode(v)
+		want := l2Squared(query, pq.Decode(code))
+		got := ADC(table, code)
+		if math.Abs(float64(want-got)) > 1e-3 {
+			t.Errorf("ADC=%v, direct decoded distance=%v", got, want)
+		}
+	}
+}
+
+// TestPQTrainRejectsTooFewVectors checks Train surfaces a clear error
+// instead of silently training degenerate (empty) codebooks when the
+// sample is smaller than k.
+func TestPQTrainRejectsTooFewVectors(t *testing.T) {
+	pq, err := NewPQ(8, 2, 256)
+	if err != nil {
+		t.Fatalf("NewPQ returned error: %v", err)
+	}
+
+	if err := pq.Train(randomVectors(10, 8), 5); err == nil {
+		t.Error("expected Train to reject a sample smaller than k")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build amd64
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+// dotAVX2 computes a dot product of 8-wide float32 chunks with FMA,
+// implemented in dot_amd64.s.
+func dotAVX2(a, b []float32) float32
+
+// dotAVX512 computes a dot product of 16-wide float32 chunks with FMA,
+// implemented in dot_amd64.s.
+func dotAVX512(a, b []float32) float32
+
+func init() {
+	dot := dotGeneric
+	switch {
+	case cpu.X86.HasAVX512F && cpu.X86.HasFMA:
+		dot = dotAVX512
+	case cpu.X86.HasAVX2 && cpu.X86.HasFMA:
+		dot = dotAVX2
+	}
+	setDot(dot)
+}
@@ -0,0 +1,17 @@
+//go:build arm64
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+// dotNEON computes a dot product using 4-wide NEON float32 lanes,
+// implemented in dot_arm64.s.
+func dotNEON(a, b []float32) float32
+
+func init() {
+	dot := dotGeneric
+	if cpu.ARM64.HasASIMD {
+		dot = dotNEON
+	}
+	setDot(dot)
+}
@@ -0,0 +1,7 @@
+//go:build !amd64 && !arm64
+
+package simd
+
+func init() {
+	setDot(dotGeneric)
+}
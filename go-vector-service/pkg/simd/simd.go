@@ -0,0 +1,99 @@
+// Package simd provides SIMD-accelerated distance kernels for the vector
+// index's hot path (CosineSimilarity/CosineDistance are evaluated on every
+// hop of HNSW's searchLayer and on every insertion). The package picks the
+// fastest available implementation for the running CPU once at init and
+// exposes it through the Dot, L2Sq, and Cosine function variables, so
+// callers never need to branch on architecture themselves.
+//
+// All three kernels are built on top of a single accelerated dot-product
+// primitive: L2Sq uses the identity ||a-b||² = a·a - 2(a·b) + b·b, and
+// Cosine divides a·b by the product of the vectors' norms. This keeps the
+// hand-written assembly surface to one routine per architecture while still
+// accelerating every kernel that matters on the search path.
+//
+// DotBatch, L2SqBatch, and CosineBatch score one query against many
+// candidates at once, for callers like BruteForceIndex.Search that would
+// otherwise call the single-pair kernels once per candidate and redo the
+// query's own dot product on every call.
+package simd
+
+import "math"
+
+// Dot computes the dot product of a and b. a and b must have equal, non-zero
+// length; behavior is undefined otherwise (callers on the search path
+// already guarantee matching dimensions).
+var Dot func(a, b []float32) float32
+
+// L2Sq computes the squared Euclidean distance between a and b.
+var L2Sq func(a, b []float32) float32
+
+// Cosine computes the cosine similarity between a and b.
+var Cosine func(a, b []float32) float32
+
+// DotBatch, L2SqBatch, and CosineBatch are the batch counterparts of Dot,
+// L2Sq, and Cosine: they score query against every vector in corpus,
+// writing into out (which must have len(corpus)). Batching amortizes
+// query-dependent work - its own dot product with itself, needed for L2Sq's
+// identity and Cosine's norm - across every candidate instead of
+// recomputing it on each call the way a scalar loop over the single-pair
+// kernels would.
+var DotBatch func(query []float32, corpus [][]float32, out []float32)
+var L2SqBatch func(query []float32, corpus [][]float32, out []float32)
+var CosineBatch func(query []float32, corpus [][]float32, out []float32)
+
+// setDot installs dot as the Dot kernel and derives L2Sq, Cosine, and their
+// batch counterparts from it. Called once from each architecture's init.
+func setDot(dot func(a, b []float32) float32) {
+	Dot = dot
+	L2Sq = func(a, b []float32) float32 {
+		return dot(a, a) - 2*dot(a, b) + dot(b, b)
+	}
+	Cosine = func(a, b []float32) float32 {
+		denom := float32(math.Sqrt(float64(dot(a, a))) * math.Sqrt(float64(dot(b, b))))
+		if denom == 0 {
+			return 0
+		}
+		return dot(a, b) / denom
+	}
+
+	DotBatch = func(query []float32, corpus [][]float32, out []float32) {
+		for i, v := range corpus {
+			out[i] = dot(query, v)
+		}
+	}
+	L2SqBatch = func(query []float32, corpus [][]float32, out []float32) {
+		qq := dot(query, query)
+		for i, v := range corpus {
+			out[i] = qq - 2*dot(query, v) + dot(v, v)
+		}
+	}
+	CosineBatch = func(query []float32, corpus [][]float32, out []float32) {
+		qNorm := float32(math.Sqrt(float64(dot(query, query))))
+		for i, v := range corpus {
+			if qNorm == 0 {
+				out[i] = 0
+				continue
+			}
+			vNorm := float32(math.Sqrt(float64(dot(v, v))))
+			if vNorm == 0 {
+				out[i] = 0
+				continue
+			}
+			out[i] = dot(query, v) / (qNorm * vNorm)
+		}
+	}
+}
+
+// dotGeneric is the pure-Go reference implementation, used as the fallback
+// on architectures/CPUs without an accelerated path and as the correctness
+// baseline in tests.
+func dotGeneric(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,110 @@
+package simd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomVector(n int) []float32 {
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = rand.Float32()*2 - 1
+	}
+	return v
+}
+
+// TestDotMatchesGeneric checks the dispatched kernel agrees with the pure-Go
+// reference within tolerance across the dimensions DocuMind embeds with.
+func TestDotMatchesGeneric(t *testing.T) {
+	for _, dim := range []int{384, 768, 1536} {
+		a := randomVector(dim)
+		b := randomVector(dim)
+
+		want := dotGeneric(a, b)
+		got := Dot(a, b)
+
+		if math.Abs(float64(want-got)) > 1e-3 {
+			t.Errorf("dim=%d: Dot=%v, dotGeneric=%v", dim, got, want)
+		}
+	}
+}
+
+// TestDotOddLength exercises the scalar tail path for lengths that aren't a
+// multiple of the SIMD lane width.
+func TestDotOddLength(t *testing.T) {
+	for _, dim := range []int{1, 3, 5, 9, 17, 31} {
+		a := randomVector(dim)
+		b := randomVector(dim)
+
+		want := dotGeneric(a, b)
+		got := Dot(a, b)
+
+		if math.Abs(float64(want-got)) > 1e-3 {
+			t.Errorf("dim=%d: Dot=%v, dotGeneric=%v", dim, got, want)
+		}
+	}
+}
+
+func TestL2SqAndCosine(t *testing.T) {
+	a := []float32{1, 0, 0}
+	b := []float32{0, 1, 0}
+
+	if got := L2Sq(a, b); math.Abs(float64(got-2)) > 1e-5 {
+		t.Errorf("L2Sq = %v, want 2", got)
+	}
+	if got := Cosine(a, b); math.Abs(float64(got)) > 1e-5 {
+		t.Errorf("Cosine = %v, want 0", got)
+	}
+
+	c := []float32{1, 0, 0}
+	if got := Cosine(a, c); math.Abs(float64(got-1)) > 1e-5 {
+		t.Errorf("Cosine = %v, want 1", got)
+	}
+}
+
+// TestCosineBatchMatchesCosine checks that CosineBatch scores each corpus
+// vector the same as calling Cosine against it individually, across a range
+// of dimensions including the scalar-tail lengths.
+func TestCosineBatchMatchesCosine(t *testing.T) {
+	for _, dim := range []int{3, 17, 384} {
+		query := randomVector(dim)
+		corpus := make([][]float32, 5)
+		for i := range corpus {
+			corpus[i] = randomVector(dim)
+		}
+
+		out := make([]float32, len(corpus))
+		CosineBatch(query, corpus, out)
+
+		for i, v := range corpus {
+			want := Cosine(query, v)
+			if math.Abs(float64(out[i]-want)) > 1e-4 {
+				t.Errorf("dim=%d corpus[%d]: CosineBatch=%v, Cosine=%v", dim, i, out[i], want)
+			}
+		}
+	}
+}
+
+// BenchmarkDotScalarVsSIMD compares the pure-Go fallback against the
+// CPU-dispatched kernel across the same embedding dimensions used in the
+// index benchmarks (384, 768, 1536).
+func BenchmarkDotScalarVsSIMD(b *testing.B) {
+	for _, dim := range []int{384, 768, 1536} {
+		v1 := randomVector(dim)
+		v2 := randomVector(dim)
+
+		b.Run(fmt.Sprintf("scalar/dim=%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dotGeneric(v1, v2)
+			}
+		})
+
+		b.Run(fmt.Sprintf("simd/dim=%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Dot(v1, v2)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package types
+
+import "strings"
+
+// Filter narrows a search to vectors whose Metadata satisfies every clause
+// set on it; omitted (zero-value) clauses are ignored. A nil Filter, and a
+// Filter with every clause left zero, both match everything.
+type Filter struct {
+	// TenantID requires an exact match on Metadata.TenantID. A ShardedIndex
+	// also uses this clause (falling back to DocumentID) to scatter a
+	// search to only the shard(s) that could hold a match, instead of
+	// every shard.
+	TenantID string `json:"tenant_id,omitempty"`
+	// DocumentID requires an exact match on Metadata.DocumentID.
+	DocumentID string `json:"document_id,omitempty"`
+	// DocumentIDPrefix requires Metadata.DocumentID to start with this prefix.
+	DocumentIDPrefix string `json:"document_id_prefix,omitempty"`
+	// PageNumberMin and PageNumberMax bound Metadata.PageNumber inclusively.
+	// Either may be left nil to leave that side of the range open.
+	PageNumberMin *int `json:"page_number_min,omitempty"`
+	PageNumberMax *int `json:"page_number_max,omitempty"`
+	// Tags requires Metadata.Tags[key] == value for every entry here.
+	Tags map[string]string `json:"tags,omitempty"`
+	// TagsIn requires Metadata.Tags[key] to be one of the listed values.
+	TagsIn map[string][]string `json:"tags_in,omitempty"`
+}
+
+// IsZero reports whether f has no clauses set, i.e. matches every vector.
+// Safe to call on a nil Filter.
+func (f *Filter) IsZero() bool {
+	if f == nil {
+		return true
+	}
+	return f.TenantID == "" && f.DocumentID == "" && f.DocumentIDPrefix == "" &&
+		f.PageNumberMin == nil && f.PageNumberMax == nil &&
+		len(f.Tags) == 0 && len(f.TagsIn) == 0
+}
+
+// Matches reports whether m satisfies every clause set on f. Safe to call
+// on a nil Filter, which matches everything.
+func (f *Filter) Matches(m Metadata) bool {
+	if f.IsZero() {
+		return true
+	}
+	if f.TenantID != "" && m.TenantID != f.TenantID {
+		return false
+	}
+	if f.DocumentID != "" && m.DocumentID != f.DocumentID {
+		return false
+	}
+	if f.DocumentIDPrefix != "" && !strings.HasPrefix(m.DocumentID, f.DocumentIDPrefix) {
+		return false
+	}
+	if f.PageNumberMin != nil && m.PageNumber < *f.PageNumberMin {
+		return false
+	}
+	if f.PageNumberMax != nil && m.PageNumber > *f.PageNumberMax {
+		return false
+	}
+	for k, v := range f.Tags {
+		if m.Tags[k] != v {
+			return false
+		}
+	}
+	for k, allowed := range f.TagsIn {
+		got, ok := m.Tags[k]
+		if !ok {
+			return false
+		}
+		found := false
+		for _, v := range allowed {
+			if got == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -4,9 +4,9 @@ package types
 // Vector represents a single embedding with its associated metadata.
 // This is the fundamental data structure for storage and search operations.
 type Vector struct {
-	ID        string   `json:"id"`
+	ID        string    `json:"id"`
 	Embedding []float32 `json:"embedding"`
-	Metadata  Metadata `json:"metadata"`
+	Metadata  Metadata  `json:"metadata"`
 }
 
 // Metadata contains information about the source document and chunk.
@@ -15,6 +15,12 @@ type Metadata struct {
 	ChunkIndex int    `json:"chunk_index"`
 	Text       string `json:"text"`
 	PageNumber int    `json:"page_number,omitempty"`
+	// Tags holds arbitrary caller-defined key/value labels (e.g. "lang":
+	// "en", "source": "upload"), queryable via Filter.Tags/TagsIn.
+	Tags map[string]string `json:"tags,omitempty"`
+	// TenantID identifies the owning tenant in multi-tenant deployments. A
+	// ShardedIndex routes by this field if set, falling back to DocumentID.
+	TenantID string `json:"tenant_id,omitempty"`
 }
 
 // SearchResult represents a single result from a vector search operation.
@@ -29,6 +35,10 @@ type InsertRequest struct {
 	ID        string    `json:"id"`
 	Embedding []float32 `json:"embedding"`
 	Metadata  Metadata  `json:"metadata"`
+	// Metric optionally names the distance metric the caller expects this
+	// index to use (e.g. "cosine", "l2"); if set, it's validated against
+	// the index's configured metric and rejected on mismatch.
+	Metric string `json:"metric,omitempty"`
 }
 
 // InsertBatchRequest is the request body for batch vector insertion.
@@ -36,11 +46,38 @@ type InsertBatchRequest struct {
 	Vectors []Vector `json:"vectors"`
 }
 
+// DeleteRequest is the request body for deleting a single vector by ID.
+type DeleteRequest struct {
+	ID string `json:"id"`
+}
+
+// DeleteBatchRequest is the request body for deleting multiple vectors.
+type DeleteBatchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// UpsertRequest is the request body for inserting a vector, or replacing it
+// if its ID already exists.
+type UpsertRequest struct {
+	ID        string    `json:"id"`
+	Embedding []float32 `json:"embedding"`
+	Metadata  Metadata  `json:"metadata"`
+	// Metric optionally names the distance metric the caller expects this
+	// index to use; see InsertRequest.Metric.
+	Metric string `json:"metric,omitempty"`
+}
+
 // SearchRequest is the request body for vector search.
 type SearchRequest struct {
 	Embedding []float32 `json:"embedding"`
 	TopK      int       `json:"top_k"`
 	Algorithm string    `json:"algorithm"` // "bruteforce" or "hnsw"
+	// Metric optionally names the distance metric the caller expects this
+	// index to use; see InsertRequest.Metric.
+	Metric string `json:"metric,omitempty"`
+	// Filter optionally restricts results to vectors whose Metadata matches
+	// every clause set on it; a nil Filter matches everything.
+	Filter *Filter `json:"filter,omitempty"`
 }
 
 // SearchResponse is the response body for vector search.
@@ -61,6 +98,27 @@ type InsertBatchResponse struct {
 	Message  string `json:"message,omitempty"`
 }
 
+// DeleteResponse is the response body for delete operations.
+type DeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// DeleteBatchResponse is the response body for batch delete operations.
+type DeleteBatchResponse struct {
+	Deleted int    `json:"deleted"`
+	Message string `json:"message,omitempty"`
+}
+
+// UpsertResponse is the response body for upsert operations.
+type UpsertResponse struct {
+	Success bool `json:"success"`
+	// Created is true if ID didn't already exist (a plain insert) and false
+	// if it replaced an existing vector.
+	Created bool   `json:"created"`
+	Message string `json:"message,omitempty"`
+}
+
 // HealthResponse is the response body for health check.
 type HealthResponse struct {
 	Status      string `json:"status"`
@@ -72,4 +130,47 @@ type StatsResponse struct {
 	VectorCount int    `json:"vector_count"`
 	Dimensions  int    `json:"dimensions"`
 	IndexType   string `json:"index_type"`
+	// TombstoneCount is how many deleted vectors are still physically
+	// present (soft-deleted, pending compaction) and so not
+	// reflected in VectorCount. Always present, even if zero, so operators
+	// can tell dead-entry buildup from an index that's simply empty.
+	TombstoneCount int `json:"tombstone_count"`
+	// PQBytesPerVector and UncompressedBytesPerVector are only populated
+	// once a Product Quantization codec has been trained via
+	// POST /index/pq/train, reporting its memory footprint reduction.
+	PQBytesPerVector           int `json:"pq_bytes_per_vector,omitempty"`
+	UncompressedBytesPerVector int `json:"uncompressed_bytes_per_vector,omitempty"`
+	// WALSizeBytes, LastSnapshotSeq, and ReplayDurationMs are only populated
+	// when the server was started with --wal-dir.
+	WALSizeBytes     int64   `json:"wal_size_bytes,omitempty"`
+	LastSnapshotSeq  uint64  `json:"last_snapshot_seq,omitempty"`
+	ReplayDurationMs float64 `json:"replay_duration_ms,omitempty"`
+	// Shards is only populated when the server was started with
+	// --num-shards greater than 1, reporting one entry per shard.
+	Shards []ShardStats `json:"shards,omitempty"`
+}
+
+// ShardStats reports one shard's size and recent search-latency
+// percentiles, as surfaced by StatsResponse.Shards.
+type ShardStats struct {
+	VectorCount  int     `json:"vector_count"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+	MemoryBytes  int64   `json:"memory_bytes"`
+}
+
+// TrainPQRequest is the request body for training a Product Quantization
+// codec, used to populate the "hnsw-pq" index mode.
+type TrainPQRequest struct {
+	Vectors    [][]float32 `json:"vectors"`
+	M          int         `json:"m"`
+	K          int         `json:"k"`
+	Iterations int         `json:"iterations"`
+}
+
+// TrainPQResponse is the response body for POST /index/pq/train.
+type TrainPQResponse struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message,omitempty"`
+	CodeSize int    `json:"code_size,omitempty"`
 }